@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	fzapi "github.com/solitonymi/go-fzapi"
+)
+
+// fzcLogger : レベルとJSON形式に対応したfzcのログ
+// FzAPIのLoggerとしても使用する
+type fzcLogger struct {
+	mu    sync.Mutex
+	level fzapi.LogLevel
+	json  bool
+}
+
+var logger = &fzcLogger{level: fzapi.LogInfo}
+
+// parseLogLevel : ログのレベルの文字列(debug|info|warn|error)を変換する
+func parseLogLevel(s string) (fzapi.LogLevel, error) {
+	for _, l := range []fzapi.LogLevel{fzapi.LogDebug, fzapi.LogInfo, fzapi.LogWarn, fzapi.LogError} {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+	return fzapi.LogInfo, fmt.Errorf("Invalid log level %s", s)
+}
+
+// Log : ログを出力する
+func (l *fzcLogger) Log(level fzapi.LogLevel, msg string, fields fzapi.LogFields) {
+	if level < l.level {
+		return
+	}
+	if l.json {
+		m := map[string]interface{}{"time": time.Now().Format(time.RFC3339Nano), "level": level.String(), "msg": msg}
+		for k, v := range fields {
+			if d, ok := v.(time.Duration); ok {
+				v = d.String()
+			}
+			m[k] = v
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			return
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		log.Writer().Write(append(b, '\n'))
+		return
+	}
+	log.Println(formatLog(level, msg, fields))
+}
+
+// formatLog : テキスト形式のログの１行
+func formatLog(level fzapi.LogLevel, msg string, fields fzapi.LogFields) string {
+	s := fmt.Sprintf("[%s] %s", strings.ToUpper(level.String()), msg)
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s += fmt.Sprintf(" %s=%v", k, fields[k])
+	}
+	return s
+}
+
+func logDebugf(format string, v ...interface{}) {
+	logger.Log(fzapi.LogDebug, fmt.Sprintf(format, v...), nil)
+}
+
+func logInfof(format string, v ...interface{}) {
+	logger.Log(fzapi.LogInfo, fmt.Sprintf(format, v...), nil)
+}
+
+func logWarnf(format string, v ...interface{}) {
+	logger.Log(fzapi.LogWarn, fmt.Sprintf(format, v...), nil)
+}
+
+func logErrorf(format string, v ...interface{}) {
+	logger.Log(fzapi.LogError, fmt.Sprintf(format, v...), nil)
+}
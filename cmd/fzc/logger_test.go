@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	fzapi "github.com/solitonymi/go-fzapi"
+)
+
+// TestFzcLogger : ログの出力の試験
+func TestFzcLogger(t *testing.T) {
+	if l, err := parseLogLevel("WARN"); err != nil || l != fzapi.LogWarn {
+		t.Errorf("parseLogLevel=%v err=%v", l, err)
+	}
+	if _, err := parseLogLevel("trace"); err == nil {
+		t.Error("parseLogLevel invalid no err")
+	}
+	s := formatLog(fzapi.LogWarn, "request", fzapi.LogFields{"status": 503, "action": "Login"})
+	if s != "[WARN] request action=Login status=503" {
+		t.Errorf("formatLog=%s", s)
+	}
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+	l := &fzcLogger{level: fzapi.LogInfo, json: true}
+	l.Log(fzapi.LogDebug, "request", nil)
+	l.Log(fzapi.LogInfo, "request", fzapi.LogFields{"latency": time.Second, "res": "OK"})
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("json err=%v %s", err, buf.String())
+	}
+	if m["level"] != "info" || m["msg"] != "request" || m["latency"] != "1s" || m["res"] != "OK" {
+		t.Errorf("json log=%v", m)
+	}
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"crypto/sha1"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"path/filepath"
@@ -19,6 +24,44 @@ import (
 var logFile *os.File
 var config *fzapi.FzcConfig
 
+// 終了コード
+const (
+	exitOK         = 0
+	exitError      = 1 // その他のエラー
+	exitNetwork    = 2 // 通信エラー
+	exitAuth       = 3 // 認証エラー
+	exitSession    = 4 // セッションの期限切れ
+	exitPermission = 5 // アクセス権限がない
+	exitQuota      = 6 // 容量の制限を超えた
+	exitNotFound   = 7 // ファイルやフォルダが見つからない
+	exitParam      = 8 // 引数や設定の誤り
+)
+
+// exitCode : エラーの分類から終了コードを決める
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, context.Canceled):
+		return exitError
+	case errors.Is(err, fzapi.ErrNetwork):
+		return exitNetwork
+	case errors.Is(err, fzapi.ErrAuth):
+		return exitAuth
+	case errors.Is(err, fzapi.ErrSessionExpired):
+		return exitSession
+	case errors.Is(err, fzapi.ErrPermission):
+		return exitPermission
+	case errors.Is(err, fzapi.ErrQuotaExceeded):
+		return exitQuota
+	case errors.Is(err, fzapi.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, fzapi.ErrInvalidParam):
+		return exitParam
+	}
+	return exitError
+}
+
 func setupConf(c *cli.Context) {
 	cpath := c.String("config")
 	master := c.String("master")
@@ -55,6 +98,51 @@ func setupConf(c *cli.Context) {
 	if c.String("to") != "" {
 		config.NotifyTo = c.String("to")
 	}
+	if cpath == "" || c.IsSet("retries") {
+		config.PlUploadRetries = c.Int("retries")
+	}
+	if c.IsSet("chunksize") {
+		config.PlUploadChunkMB = c.Int("chunksize")
+	}
+	if c.IsSet("threshold") {
+		config.PlUploadThresholdMB = c.Int("threshold")
+	}
+	if c.IsSet("parallel") {
+		config.PlUploadParallel = c.Int("parallel")
+	}
+	if cpath == "" || c.IsSet("retrymax") {
+		config.RetryMax = c.Int("retrymax")
+	}
+	if cpath == "" || c.IsSet("retrywait") {
+		config.RetryWait = c.Int("retrywait")
+	}
+	if c.IsSet("proxy") {
+		config.Proxy = c.String("proxy")
+	}
+	if c.IsSet("proxyuser") {
+		config.ProxyUser = c.String("proxyuser")
+	}
+	if c.IsSet("proxypass") {
+		config.ProxyPassword = c.String("proxypass")
+	}
+	if c.IsSet("tlsmin") {
+		config.TLSMinVersion = c.String("tlsmin")
+	}
+	if c.IsSet("ratelimit") {
+		config.RateLimit = c.String("ratelimit")
+	}
+	if c.IsSet("translimit") {
+		config.TransferRateLimit = c.String("translimit")
+	}
+	if c.IsSet("ratesched") {
+		config.RateSchedule = c.StringSlice("ratesched")
+	}
+	if c.IsSet("mailmax") {
+		config.MailMaxFileSize = c.String("mailmax")
+	}
+	if c.IsSet("mailtotal") {
+		config.MailMaxTotalSize = c.String("mailtotal")
+	}
 	logDir := c.String("log")
 	if logDir != "" {
 		path := filepath.Join(logDir, time.Now().Format("20060102")+".log")
@@ -65,6 +153,12 @@ func setupConf(c *cli.Context) {
 		}
 		log.SetOutput(io.MultiWriter(logFile, os.Stderr))
 	}
+	level, err := parseLogLevel(c.String("loglevel"))
+	if err != nil {
+		log.Fatalf("setupConf err=%v", err)
+	}
+	logger.level = level
+	logger.json = c.Bool("logjson")
 }
 
 func main() {
@@ -111,6 +205,25 @@ func Run() int {
 				return mbSend(c)
 			},
 		},
+		{
+			Name:  "find",
+			Usage: "Find files in FileZen folders",
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "project", Usage: "Project name `PATTERN`"},
+				&cli.StringFlag{Name: "folder", Usage: "Folder path `PATTERN` (folder/subfolder)"},
+				&cli.StringFlag{Name: "name", Usage: "File name `PATTERN`"},
+				&cli.StringFlag{Name: "owner", Usage: "File owner `PATTERN`"},
+				&cli.BoolFlag{Name: "regex", Usage: "Use regular expressions instead of glob patterns"},
+				&cli.StringFlag{Name: "minsize", Usage: "Minimum file `SIZE` (e.g. 10M)"},
+				&cli.StringFlag{Name: "maxsize", Usage: "Maximum file `SIZE` (e.g. 1G)"},
+				&cli.StringFlag{Name: "after", Usage: "Files registered at or after `DATETIME` (2006/01/02 15:04)"},
+				&cli.StringFlag{Name: "before", Usage: "Files registered before `DATETIME` (2006/01/02 15:04)"},
+			},
+			Action: func(c *cli.Context) error {
+				setupConf(c)
+				return findFiles(c)
+			},
+		},
 		{
 			Name:  "import",
 			Usage: "import [fzuser|]",
@@ -225,6 +338,14 @@ func Run() int {
 			Usage: "FileZen `Mail Config File`",
 			Value: "",
 		},
+		&cli.StringFlag{
+			Name:  "mbstart",
+			Usage: "FileZen Mail publication start `DATETIME` (2006/01/02 15:04)",
+		},
+		&cli.IntFlag{
+			Name:  "mbdays",
+			Usage: "FileZen Mail publication `DAYS`",
+		},
 		&cli.StringFlag{
 			Name:  "out",
 			Usage: "FileZen Config outpu `FILE`",
@@ -235,6 +356,78 @@ func Run() int {
 			Usage: "Target `UID`",
 			Value: "",
 		},
+		&cli.IntFlag{
+			Name:  "retries",
+			Usage: "Retry `COUNT` of each upload chunk",
+			Value: 3,
+		},
+		&cli.IntFlag{
+			Name:  "chunksize",
+			Usage: "Upload chunk `MB` (default 50)",
+		},
+		&cli.IntFlag{
+			Name:  "threshold",
+			Usage: "Chunked upload file size `MB` (default chunksize)",
+		},
+		&cli.IntFlag{
+			Name:  "parallel",
+			Usage: "Upload chunks in parallel `COUNT` (default 1)",
+		},
+		&cli.IntFlag{
+			Name:  "retrymax",
+			Usage: "Max `ATTEMPTS` of requests on transient failures",
+			Value: 3,
+		},
+		&cli.IntFlag{
+			Name:  "retrywait",
+			Usage: "First retry wait `SECONDS` (doubled on each retry)",
+			Value: 1,
+		},
+		&cli.StringFlag{
+			Name:  "proxy",
+			Usage: "HTTP proxy `URL` (default HTTP_PROXY/HTTPS_PROXY/NO_PROXY)",
+		},
+		&cli.StringFlag{
+			Name:  "proxyuser",
+			Usage: "HTTP proxy basic auth `USER`",
+		},
+		&cli.StringFlag{
+			Name:  "proxypass",
+			Usage: "HTTP proxy basic auth `PASSWORD`",
+		},
+		&cli.StringFlag{
+			Name:  "tlsmin",
+			Usage: "TLS minimum `VERSION` (1.2 or 1.3)",
+		},
+		&cli.StringFlag{
+			Name:  "ratelimit",
+			Usage: "Total transfer rate limit `BYTES/SEC` (e.g. 10M)",
+		},
+		&cli.StringFlag{
+			Name:  "translimit",
+			Usage: "Rate limit of each transfer `BYTES/SEC` (e.g. 512K)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "ratesched",
+			Usage: "Rate limit by time of day `HH:MM-HH:MM=TOTAL/EACH` (e.g. 09:00-18:00=1M/256K)",
+		},
+		&cli.StringFlag{
+			Name:  "mailmax",
+			Usage: "FileZen Mail max `SIZE` of each file (e.g. 200M)",
+		},
+		&cli.StringFlag{
+			Name:  "mailtotal",
+			Usage: "FileZen Mail max total `SIZE` of a mail (e.g. 1G)",
+		},
+		&cli.StringFlag{
+			Name:  "loglevel",
+			Usage: "Log `LEVEL` debug|info|warn|error",
+			Value: "info",
+		},
+		&cli.BoolFlag{
+			Name:  "logjson",
+			Usage: "Log in JSON format",
+		},
 		&cli.StringFlag{
 			Name:     "log",
 			Usage:    "Log `DIR`",
@@ -248,12 +441,25 @@ func Run() int {
 			logFile.Close()
 		}
 	}()
-	err := app.Run(os.Args)
+	// シグナルを受けた場合は、実行中の転送をキャンセルして終了する
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	go func() {
+		select {
+		case s := <-sig:
+			logWarnf("Cancel by signal %v", s)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	err := app.RunContext(ctx, os.Args)
 	if err != nil {
-		log.Println(err)
-		return 1
+		logErrorf("%v", err)
 	}
-	return 0
+	return exitCode(err)
 }
 
 func makeFzConfig(c *cli.Context) error {
@@ -268,7 +474,7 @@ func testLogin(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	defer fz.FzLogout()
+	defer fz.FzLogoutContext(context.Background())
 	return nil
 }
 
@@ -279,69 +485,95 @@ func syncFolder(c *cli.Context) error {
 	if config.LocalFolder == "" {
 		return fmt.Errorf("No local Folder")
 	}
+	var downPath, upPath fzapi.FzPath
+	var err error
+	if config.FzDownFolder != "" {
+		if downPath, err = fzapi.ParseFzPath(config.FzDownFolder); err != nil {
+			return fmt.Errorf("Download folder: %w", err)
+		}
+	}
+	if config.FzUpFolder != "" {
+		if upPath, err = fzapi.ParseFzPath(config.FzUpFolder); err != nil {
+			return fmt.Errorf("Upload folder: %w", err)
+		}
+	}
 	// Check Dirs
 	if !checkDir("Download") || !checkDir("Upload") || !checkDir("fztmp") {
 		return fmt.Errorf("checkDirs Error")
 	}
-	log.Println("Start FzSync")
+	logInfof("Start FzSync")
 	fz, err := loginToFileZen(c)
 	if err != nil {
 		return err
 	}
-	defer fz.FzLogout()
+	defer fz.FzLogoutContext(context.Background())
 	// Download
-	d := fz.FzFindFolder(config.FzDownFolder)
-	if config.FzDownFolder != "" && d.ID == "" {
-		return fmt.Errorf("Download folder not found. %s", config.FzDownFolder)
+	d := &fzapi.XMLFolder{}
+	if config.FzDownFolder != "" {
+		if err := fz.CheckPermission(downPath, fzapi.PermRead); err != nil {
+			return fmt.Errorf("Download folder %s: %w", config.FzDownFolder, err)
+		}
+		if d, err = fz.FindFolder(downPath); err != nil {
+			return fmt.Errorf("Download folder %s: %w", config.FzDownFolder, err)
+		}
 	}
 	for _, f := range d.FileList {
 		if f.Key == "" {
 			continue
 		}
+		// 同期に必要なのは名前とサイズだけなので、他の項目は変換しない
+		size, err := strconv.ParseInt(f.Size, 10, 64)
+		if err != nil {
+			size = -1
+		}
 		localfile := filepath.Join(config.LocalFolder, "/Download/", f.Name)
 		fstat, err := os.Stat(localfile)
 		if err != nil {
-			log.Printf("Download Start %s\n", f.Name)
-			st := time.Now().Unix()
-			err = fz.FzDownload(f.Key, localfile)
+			logInfof("Download Start %s", f.Name)
+			st := time.Now()
+			err = fz.FzDownloadContext(c.Context, f.Key, localfile)
 			if err == nil {
-				et := time.Now().Unix()
-				s, err := strconv.ParseInt(f.Size, 10, 64)
-				if err != nil {
-					s = 0
-				}
-				dt := et - st
+				dt := time.Since(st).Seconds()
 				speed := "-"
-				if dt > 0 {
-					speed = fmt.Sprintf("%.3fKbps", float64(s)/(1024.0*float64(dt)))
+				if dt > 0 && size >= 0 {
+					speed = fmt.Sprintf("%.3fKbps", float64(size)/(1024.0*dt))
 				}
-				log.Printf("Download Done %s speed=%s \n", f.Name, speed)
+				logInfof("Download Done %s speed=%s", f.Name, speed)
 			} else {
-				log.Printf("Download Failed %s\n", f.Name)
-				fz.FzReload()
+				logErrorf("Download Failed %s err=%v", f.Name, err)
+				if c.Context.Err() != nil {
+					return c.Context.Err()
+				}
+				fz.FzReloadContext(c.Context)
 			}
 		} else {
-			if fmt.Sprintf("%d", fstat.Size()) != f.Size {
-				log.Printf("File size mismatch %s\n", f.Name)
+			if size < 0 {
+				logWarnf("Unknown file size %s size=%s", f.Name, f.Size)
+			} else if fstat.Size() != size {
+				logWarnf("File size mismatch %s", f.Name)
 			}
 		}
 	}
 	// Upload
+	if config.FzUpFolder == "" {
+		logInfof("End Folder Sync")
+		return nil
+	}
 	uppath := filepath.Join(config.LocalFolder, "/Upload", "/*")
 	files, _ := filepath.Glob(uppath)
-	ud := fz.FzFindFolder(config.FzUpFolder)
-	if config.FzUpFolder != "" && ud.ID == "" {
-		return fmt.Errorf("Upload folder not found. %s", config.FzUpFolder)
+	if _, err := fz.CanUploadTo(upPath, ""); err != nil {
+		return fmt.Errorf("Upload folder %s: %w", config.FzUpFolder, err)
 	}
-	if !fz.CanUpload(config.FzUpFolder, "") {
-		return fmt.Errorf("Upload Failed No Perimission %s", config.FzUpFolder)
+	opts, err := config.UploadOptions("", "")
+	if err != nil {
+		return fmt.Errorf("Upload Failed Invalid notify setting: %w", err)
 	}
 	for _, f := range files {
 		f, _ = filepath.Abs(f)
 		fstat, err := os.Stat(f)
 		bZip := false
 		if err != nil {
-			log.Printf("Upload Skip stat error %s\n", f)
+			logWarnf("Upload Skip stat error %s", f)
 			continue
 		}
 		bf := filepath.Base(f)
@@ -349,45 +581,64 @@ func syncFolder(c *cli.Context) error {
 			bZip = true
 			bf += ".zip"
 		}
-		if fz.CanUpload(config.FzUpFolder, bf) {
+		if ok, _ := fz.CanUploadTo(upPath, bf); ok {
+			// 中断した分割アップロードの状態
+			stateFile := filepath.Join(config.LocalFolder, "fztmp", bf+".plupload")
 			if bZip {
-				f = makeZip(bf, f)
+				zipfile, _ := filepath.Abs(filepath.Join(config.LocalFolder, "fztmp", bf))
+				if isExists(stateFile) && isExists(zipfile) {
+					logInfof("Resume upload with zip file %s", zipfile)
+					f = zipfile
+				} else {
+					f = makeZip(bf, f)
+				}
 				fstat, err = os.Stat(f)
 				if err != nil {
-					log.Printf("Skip upload make zip error  %s\n", f)
+					logWarnf("Skip upload make zip error  %s", f)
 					continue
 				}
 			}
 			com := getFileComment(f)
-			d = fz.FzFindFolder(config.FzUpFolder)
-			st := time.Now().Unix()
-			if fstat.Size() > 1024*1024*50 {
-				err = fz.FzPlUpload(f, d.ID, bf, com, config.NotifyTo, config.NotifyMode)
+			ud, err := fz.FindFolder(upPath)
+			if err != nil {
+				return fmt.Errorf("Upload folder %s: %w", config.FzUpFolder, err)
+			}
+			st := time.Now()
+			opts.RegName = bf
+			opts.Description = com
+			if fz.UsePlUpload(fstat.Size()) {
+				err = fz.FzPlUploadResumeWithOptions(c.Context, stateFile, f, ud.ID, opts)
 			} else {
-				err = fz.FzUpload(f, d.ID, bf, com, config.NotifyTo, config.NotifyMode)
+				err = fz.FzUploadWithOptions(c.Context, f, ud.ID, opts)
 			}
 			if err == nil {
-				et := time.Now().Unix()
-				dt := et - st
+				dt := time.Since(st).Seconds()
 				speed := "-"
 				if dt > 0 {
-					speed = fmt.Sprintf("%.3fKbps", float64(fstat.Size())/(1024.0*float64(dt)))
+					speed = fmt.Sprintf("%.3fKbps", float64(fstat.Size())/(1024.0*dt))
 				}
-				log.Printf("Upload Done %s speed=%s\n", bf, speed)
+				logInfof("Upload Done %s speed=%s", bf, speed)
 			} else {
-				log.Printf("Upload Failed %s err=%v\n", bf, err)
-				fz.FzReload()
+				logErrorf("Upload Failed %s err=%v", bf, err)
+				if c.Context.Err() == nil {
+					fz.FzReloadContext(c.Context)
+				}
 			}
-			if bZip {
-				log.Printf("Delete  temp zip file %s\n", f)
+			if bZip && err != nil && isExists(stateFile) {
+				logInfof("Keep temp zip file to resume %s", f)
+			} else if bZip {
+				logDebugf("Delete  temp zip file %s", f)
 				err = os.Remove(f)
 				if err != nil {
-					log.Println(err)
+					logWarnf("Delete temp zip file err=%v", err)
 				}
 			}
+			if c.Context.Err() != nil {
+				return c.Context.Err()
+			}
 		}
 	}
-	log.Println("End Folder Sync")
+	logInfof("End Folder Sync")
 	return nil
 }
 
@@ -398,10 +649,10 @@ func isExists(filename string) bool {
 func checkDir(dir string) bool {
 	d := filepath.Join(config.LocalFolder, dir)
 	if !isExists(d) {
-		log.Printf("Create dir '%s'", d)
+		logInfof("Create dir '%s'", d)
 		err := os.Mkdir(d, 0777)
 		if err != nil {
-			log.Println(err)
+			logErrorf("Create dir err=%v", err)
 			return false
 		}
 	}
@@ -439,7 +690,7 @@ func getFileComment(f string) string {
 func makeZip(bf, f string) string {
 	zipfile := filepath.Join(config.LocalFolder, "/fztmp/", bf)
 	zipfile, _ = filepath.Abs(zipfile)
-	log.Printf("Make Zip file '%s' from dir '%s'", zipfile, f)
+	logInfof("Make Zip file '%s' from dir '%s'", zipfile, f)
 	zip := new(archivex.ZipFile)
 	zip.Create(zipfile)
 	zip.AddAll(f, true)
@@ -455,12 +706,70 @@ func mbSend(c *cli.Context) error {
 	if _, err := os.Stat(mbconf); err != nil {
 		return err
 	}
+	job, err := fzapi.LoadMailJob(mbconf)
+	if err != nil {
+		return err
+	}
+	if c.IsSet("mbstart") {
+		if job.Start, err = fzapi.ParseMailTime(c.String("mbstart")); err != nil {
+			return err
+		}
+	}
+	if c.IsSet("mbdays") {
+		job.Days = c.Int("mbdays")
+	}
 	fz, err := loginToFileZen(c)
 	if err != nil {
 		return err
 	}
-	defer fz.FzLogout()
-	return fz.FzMail(mbconf)
+	defer fz.FzLogoutContext(context.Background())
+	return fz.FzSendMailJob(c.Context, job)
+}
+
+func findFiles(c *cli.Context) error {
+	s := &fzapi.SearchFzFileEnt{
+		Project: c.String("project"),
+		Folder:  c.String("folder"),
+		File:    c.String("name"),
+		Owner:   c.String("owner"),
+		Regexp:  c.Bool("regex"),
+	}
+	var err error
+	if s.MinSize, err = fzapi.ParseSize(c.String("minsize")); err != nil {
+		return err
+	}
+	if s.MaxSize, err = fzapi.ParseSize(c.String("maxsize")); err != nil {
+		return err
+	}
+	if c.String("after") != "" {
+		if s.After, err = fzapi.ParseMailTime(c.String("after")); err != nil {
+			return err
+		}
+	}
+	if c.String("before") != "" {
+		if s.Before, err = fzapi.ParseMailTime(c.String("before")); err != nil {
+			return err
+		}
+	}
+	fz, err := loginToFileZen(c)
+	if err != nil {
+		return err
+	}
+	defer fz.FzLogoutContext(context.Background())
+	files, err := fz.SearchFzFile(s)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FOLDER\tNAME\tSIZE\tREGISTERED\tOWNER\tKEY")
+	for _, m := range files {
+		ts := "-"
+		if !m.Info.ModTime.IsZero() {
+			ts = m.Info.ModTime.Format("2006/01/02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", m.Path, m.Info.Name, m.Info.Size, ts, m.Info.Owner, m.Info.Key)
+	}
+	return w.Flush()
 }
 
 func adminImport(c *cli.Context) error {
@@ -471,8 +780,8 @@ func adminImport(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	defer fz.FzLogout()
-	if err := fz.AdminImport(c.Args().Get(0), c.String("csv")); err != nil {
+	defer fz.FzLogoutContext(context.Background())
+	if err := fz.AdminImportContext(c.Context, c.Args().Get(0), c.String("csv")); err != nil {
 		return err
 	}
 	return nil
@@ -486,8 +795,8 @@ func adminExport(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	defer fz.FzLogout()
-	if err := fz.AdminExport(c.Args().Get(0), c.String("csv"), c.String("start"), c.String("end")); err != nil {
+	defer fz.FzLogoutContext(context.Background())
+	if err := fz.AdminExportContext(c.Context, c.Args().Get(0), c.String("csv"), c.String("start"), c.String("end")); err != nil {
 		return err
 	}
 	return nil
@@ -501,8 +810,8 @@ func mbImport(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	defer fz.FzLogout()
-	if err := fz.MbAdminImport(c.Args().Get(0), c.String("tuid"), c.String("csv")); err != nil {
+	defer fz.FzLogoutContext(context.Background())
+	if err := fz.MbAdminImportContext(c.Context, c.Args().Get(0), c.String("tuid"), c.String("csv")); err != nil {
 		return err
 	}
 	return nil
@@ -516,21 +825,39 @@ func mbExport(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	defer fz.FzLogout()
-	if err := fz.MbAdminExport(c.Args().Get(0), c.String("tuid"), c.String("csv")); err != nil {
+	defer fz.FzLogoutContext(context.Background())
+	if err := fz.MbAdminExportContext(c.Context, c.Args().Get(0), c.String("tuid"), c.String("csv")); err != nil {
 		return err
 	}
 	return nil
 }
 
 func loginToFileZen(c *cli.Context) (*fzapi.FzAPI, error) {
-	fz := &fzapi.FzAPI{}
+	to, err := config.TransportOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts := []fzapi.Option{fzapi.WithTransportOptions(to), fzapi.WithLogger(logger), fzapi.WithRetry(config.RetryOptions())}
 	if c.String("cert") != "" && c.String("keypass") != "" {
-		if err := fz.LoadClientCert(c.String("cert"), c.String("keypass")); err != nil {
-			return nil, err
-		}
+		opts = append(opts, fzapi.WithClientCertFile(c.String("cert"), c.String("keypass")))
+	}
+	fz, err := fzapi.NewClient(config.FzURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	fz.PlUpload = config.PlUploadOptions()
+	fz.PlUpload.RetryWait = 5 * time.Second
+	fz.Progress = newProgressView()
+	rl, err := config.RateLimitOptions()
+	if err != nil {
+		return nil, err
+	}
+	fz.RateLimit = rl
+	if fz.MailLimit, err = config.MailLimitOptions(); err != nil {
+		return nil, err
 	}
-	if err := fz.FzLogin(config.FzURL, config.FzUID, config.FzPassword); err != nil {
+	cred := fzapi.StaticCredentials(config.FzUID, config.FzPassword)
+	if err := fz.FzLoginWithCredentials(c.Context, "", cred); err != nil {
 		return nil, err
 	}
 	return fz, nil
@@ -25,7 +25,9 @@ func TestFzc(t *testing.T) {
 	}
 	// Error Case
 	cmd := "fzc -url a -uid b -passwd c -local test -upload test/upload -download test/download test"
-	doTest(t, cmd, 1)
+	doTest(t, cmd, exitParam)
+	cmd = "fzc -retrymax 1 -url http://127.0.0.1:1 -uid b -passwd c -local test -upload test/upload -download test/download test"
+	doTest(t, cmd, exitNetwork)
 	url := os.Getenv("FZ_URL")
 	if url == "" {
 		t.Fatal("No Url")
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	fzapi "github.com/solitonymi/go-fzapi"
+)
+
+// progressLogInterval : 端末以外に進捗をログ出力する間隔
+const progressLogInterval = 10 * time.Second
+
+// progressView : 転送の進捗を表示する
+// 端末の場合はプログレスバーを表示し、それ以外の場合は定期的にログに出力する
+type progressView struct {
+	mu   sync.Mutex
+	out  io.Writer
+	tty  bool
+	last map[string]time.Time
+}
+
+// newProgressView : 標準エラー出力に進捗を表示する
+func newProgressView() *progressView {
+	return &progressView{out: os.Stderr, tty: isTerminal(os.Stderr), last: map[string]time.Time{}}
+}
+
+// isTerminal : 端末か判定する
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// OnProgress : 進捗を表示する
+func (v *progressView) OnProgress(p fzapi.Progress) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.tty {
+		fmt.Fprintf(v.out, "\r%s\x1b[K", progressLine(p, true))
+		if p.Finished {
+			fmt.Fprintln(v.out)
+		}
+		return
+	}
+	if p.Finished {
+		delete(v.last, p.Name)
+		logInfof("Progress %s", progressLine(p, false))
+		return
+	}
+	if time.Since(v.last[p.Name]) >= progressLogInterval {
+		v.last[p.Name] = time.Now()
+		logInfof("Progress %s", progressLine(p, false))
+	}
+}
+
+// progressLine : 進捗を１行の文字列にする
+func progressLine(p fzapi.Progress, bar bool) string {
+	s := p.Name
+	if p.Total > 0 {
+		// 宣言されたサイズが実際より小さい場合などに、範囲を超えないようにする
+		pct := int(p.Done * 100 / p.Total)
+		if pct < 0 {
+			pct = 0
+		} else if pct > 100 {
+			pct = 100
+		}
+		if bar {
+			n := pct / 5
+			s += " [" + strings.Repeat("=", n) + strings.Repeat(" ", 20-n) + "]"
+		}
+		s += fmt.Sprintf(" %3d%% %s/%s", pct, formatBytes(p.Done), formatBytes(p.Total))
+	} else {
+		s += " " + formatBytes(p.Done)
+	}
+	if p.Chunks > 0 {
+		s += fmt.Sprintf(" chunk=%d/%d", p.Chunk, p.Chunks)
+	}
+	s += " " + formatBytes(int64(p.Rate)) + "/s"
+	if p.Finished {
+		s += fmt.Sprintf(" time=%s", p.Elapsed.Round(time.Second))
+	} else if p.ETA >= 0 {
+		s += fmt.Sprintf(" ETA=%s", p.ETA.Round(time.Second))
+	}
+	return s
+}
+
+// formatBytes : バイト数を単位付きの文字列にする
+func formatBytes(n int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	f := float64(n)
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d%s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f%s", f, units[i])
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	fzapi "github.com/solitonymi/go-fzapi"
+)
+
+// TestProgressLine : 進捗の表示の試験
+func TestProgressLine(t *testing.T) {
+	tests := []struct {
+		p    fzapi.Progress
+		bar  bool
+		want string
+	}{
+		{fzapi.Progress{Name: "a.zip", Done: 512, Total: 1024, Rate: 2048, ETA: 3 * time.Second}, true, "a.zip [==========          ]  50% 512B/1.0KB 2.0KB/s ETA=3s"},
+		{fzapi.Progress{Name: "a.zip", Done: 50 << 20, Total: 100 << 20, Chunk: 2, Chunks: 2, Rate: 1 << 20, ETA: -1}, false, "a.zip  50% 50.0MB/100.0MB chunk=2/2 1.0MB/s"},
+		{fzapi.Progress{Name: "c.bin", Done: 2048, Total: 1024, Rate: 1024, ETA: -1}, true, "c.bin [====================] 100% 2.0KB/1.0KB 1.0KB/s"},
+		{fzapi.Progress{Name: "c.bin", Done: -1, Total: 1024, Rate: 0, ETA: -1}, true, "c.bin [                    ]   0% -1B/1.0KB 0B/s"},
+		{fzapi.Progress{Name: "b.csv", Done: 100, Total: -1, Rate: 100, Elapsed: time.Second, Finished: true}, false, "b.csv 100B 100B/s time=1s"},
+	}
+	for _, tt := range tests {
+		if got := progressLine(tt.p, tt.bar); got != tt.want {
+			t.Errorf("progressLine got=%q want=%q", got, tt.want)
+		}
+	}
+}
@@ -24,10 +24,24 @@ func init() {
 	flag.StringVar(&config.FzUID, "uid", "", "FileZen user ID")
 	flag.StringVar(&config.FzPassword, "passwd", "", "FileZen password")
 	flag.StringVar(&config.LocalFolder, "local", "", "FileZen local folder")
-	flag.StringVar(&config.FzUpFolder, "upload", "", "FileZen upload project/folder")
-	flag.StringVar(&config.FzDownFolder, "download", "", "FileZen download project/folder")
+	flag.StringVar(&config.FzUpFolder, "upload", "", "FileZen upload project/folder (escape / and \\ in names with \\)")
+	flag.StringVar(&config.FzDownFolder, "download", "", "FileZen download project/folder (escape / and \\ in names with \\)")
 	flag.StringVar(&config.NotifyMode, "notifymode", "", "FileZen Notify Mode DOWNLOAD|DELETE|")
 	flag.StringVar(&config.NotifyTo, "notifyto", "", "FileZen Notify Mail to ALL|AUTO")
+	flag.IntVar(&config.PlUploadRetries, "retries", 3, "FileZen upload chunk retry count")
+	flag.IntVar(&config.PlUploadChunkMB, "chunksize", 0, "FileZen upload chunk size MB (default 50)")
+	flag.IntVar(&config.PlUploadThresholdMB, "threshold", 0, "FileZen chunked upload file size MB (default chunksize)")
+	flag.IntVar(&config.PlUploadParallel, "parallel", 0, "FileZen upload chunks in parallel (default 1)")
+	flag.StringVar(&config.Proxy, "proxy", "", "HTTP proxy URL")
+	flag.StringVar(&config.ProxyUser, "proxyuser", "", "HTTP proxy basic auth user")
+	flag.StringVar(&config.ProxyPassword, "proxypass", "", "HTTP proxy basic auth password")
+	flag.StringVar(&config.TLSMinVersion, "tlsmin", "", "TLS minimum version 1.2|1.3")
+	flag.IntVar(&config.RetryMax, "retrymax", 3, "FileZen max attempts of requests on transient failures")
+	flag.IntVar(&config.RetryWait, "retrywait", 1, "FileZen first retry wait seconds")
+	flag.StringVar(&config.RateLimit, "ratelimit", "", "FileZen total transfer rate limit bytes/sec (e.g. 10M)")
+	flag.StringVar(&config.MailMaxFileSize, "mailmax", "", "FileZen Mail max size of each file (e.g. 200M)")
+	flag.StringVar(&config.MailMaxTotalSize, "mailtotal", "", "FileZen Mail max total size of a mail (e.g. 1G)")
+	flag.StringVar(&config.TransferRateLimit, "translimit", "", "FileZen rate limit of each transfer bytes/sec (e.g. 512K)")
 }
 
 func main() {
@@ -64,6 +78,21 @@ func Run() int {
 		flag.Usage()
 		return 1
 	}
+	for _, f := range []string{config.FzUpFolder, config.FzDownFolder} {
+		if f == "" {
+			continue
+		}
+		if _, err := fzapi.ParseFzPath(f); err != nil {
+			log.Println(err)
+			flag.Usage()
+			return 1
+		}
+	}
+	if _, err := config.UploadOptions("", ""); err != nil {
+		log.Println(err)
+		flag.Usage()
+		return 1
+	}
 	if err := fzapi.SaveFzcConfig(&config, path, MasterKey); err != nil {
 		log.Println(err)
 		return 2
@@ -2,7 +2,7 @@ package fzapi
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	crand "crypto/rand"
@@ -18,7 +18,6 @@ import (
 	"io"
 	"io/ioutil"
 	"math/rand"
-	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
@@ -26,6 +25,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jhoonb/archivex"
@@ -35,9 +35,6 @@ import (
 // FileZenRAUserAgent : User-Agentは、子機と同じ、FileZenの設定で制御可能
 const FileZenRAUserAgent = "FileZenRA"
 
-// gBody : 分割アップロード用のバッファ
-var gBody = &bytes.Buffer{}
-
 // XMLFile : FileZenの応答内のファイルを表すstruct
 type XMLFile struct {
 	XMLName   xml.Name `xml:"File"`
@@ -51,13 +48,15 @@ type XMLFile struct {
 }
 
 // XMLFolder : FileZenの応答内のフォルダを表すstruct
+// SubFoldersは、FileZenがサブフォルダをFolderの中のFolder要素で返すと想定したもの（未検証）
 type XMLFolder struct {
-	XMLName  xml.Name   `xml:"Folder"`
-	Name     string     `xml:"Name,attr"`
-	Access   string     `xml:"Access,attr"`
-	ID       string     `xml:"Id,attr"`
-	Limit    string     `xml:"Limit,attr"`
-	FileList []*XMLFile `xml:"File"`
+	XMLName    xml.Name     `xml:"Folder"`
+	Name       string       `xml:"Name,attr"`
+	Access     string       `xml:"Access,attr"`
+	ID         string       `xml:"Id,attr"`
+	Limit      string       `xml:"Limit,attr"`
+	FileList   []*XMLFile   `xml:"File"`
+	SubFolders []*XMLFolder `xml:"Folder"`
 }
 
 // XMLProject : FileZenの応答内のプロジェクトを表すstruct
@@ -79,6 +78,11 @@ type XMLFileZen struct {
 }
 
 // FzAPI : FileZen APIを表すstruct
+//
+// ログイン後は、複数のgoroutineから同時に使用できる。
+// セッションやLastRespの更新は内部でロックして行い、参照はLastResponse()を使用する。
+// URLや証明書などのフィールドの変更と、LastRespの内容の直接の変更は、
+// 並行に使用する前（ログインする前）に行うこと。
 type FzAPI struct {
 	URL                string
 	InsecureSkipVerify bool
@@ -88,11 +92,87 @@ type FzAPI struct {
 	ClientCert         tls.Certificate
 	FzSession          http.Cookie
 	LastResp           *XMLFileZen
+	Credentials        CredentialProvider // 自動再ログインに使用する認証情報
+	OnRelogin          func(ReloginEvent) // 再ログインした時に呼ばれる
+	PlUpload           PlUploadOptions    // 分割アップロードの設定
+	Progress           ProgressObserver   // 転送の進捗を受け取る
+	RateLimit          RateLimit          // 転送速度の制限
+	Retry              RetryPolicy        // 一時的な失敗の再試行の設定
+	HTTPClient         *http.Client       // 使用するHTTPクライアント（指定した場合は、通信の設定を無視する）
+	Transport          http.RoundTripper  // 使用するRoundTripper（指定した場合は、TLSとTransportOptionsを無視する）
+	TransportOptions   TransportOptions   // HTTPの通信の設定
+	Logger             Logger             // リクエストなどのログの出力先
+	UserAgent          string             // User-Agent（空の場合はFileZenRAUserAgent）
+	MailLimit          MailLimit          // めるあど便の添付ファイルのサイズの上限
 	client             *http.Client
+	mu                 sync.RWMutex // client, URL, FzSession, LastRespを保護する
+	reloginMu          sync.Mutex   // 再ログインを１つにする
+	limiter            *limiter     // 全ての転送の合計の速度の制限
+	limitOnce          sync.Once
+}
+
+// httpClient : HTTPクライアントを取得する（最初の呼び出しで作成する）
+func (fz *FzAPI) httpClient() *http.Client {
+	fz.mu.RLock()
+	c := fz.client
+	fz.mu.RUnlock()
+	if c != nil {
+		return c
+	}
+	fz.mu.Lock()
+	defer fz.mu.Unlock()
+	if fz.client == nil {
+		fz.getHTTPClient()
+	}
+	return fz.client
+}
+
+// baseURL : FileZenのURLを取得する
+func (fz *FzAPI) baseURL() string {
+	fz.mu.RLock()
+	defer fz.mu.RUnlock()
+	return fz.URL
+}
+
+// session : セッションIDのクッキーを取得する
+func (fz *FzAPI) session() http.Cookie {
+	fz.mu.RLock()
+	defer fz.mu.RUnlock()
+	return fz.FzSession
+}
+
+// addSession : リクエストにセッションIDのクッキーを追加する
+func (fz *FzAPI) addSession(req *http.Request) {
+	c := fz.session()
+	req.AddCookie(&c)
+}
+
+// validKey : 最後の応答のValidKeyを取得する
+func (fz *FzAPI) validKey() string {
+	if r := fz.LastResponse(); r != nil {
+		return r.ValidKey
+	}
+	return ""
+}
+
+// LastResponse : 最後に成功した応答を取得する
+// 複数のgoroutineから使用する場合は、LastRespの代わりにこちらを使用する
+func (fz *FzAPI) LastResponse() *XMLFileZen {
+	fz.mu.RLock()
+	defer fz.mu.RUnlock()
+	return fz.LastResp
 }
 
 // getHTTPClient : TLSの設定などを使って、ＨＴＴＰクライアントを作成する
 func (fz *FzAPI) getHTTPClient() {
+	if fz.HTTPClient != nil {
+		fz.client = fz.HTTPClient
+		return
+	}
+	if fz.Transport != nil {
+		fz.client = &http.Client{Timeout: time.Duration(fz.Timeout) * time.Second, Transport: fz.Transport}
+		return
+	}
 	tlsConfig := &tls.Config{InsecureSkipVerify: fz.InsecureSkipVerify}
 	if len(fz.CaCert) > 0 {
 		caCertPool := x509.NewCertPool()
@@ -102,9 +182,7 @@ func (fz *FzAPI) getHTTPClient() {
 	if fz.UseClientCert {
 		tlsConfig.Certificates = []tls.Certificate{fz.ClientCert}
 	}
-	tr := &http.Transport{
-		TLSClientConfig: tlsConfig,
-	}
+	tr := fz.TransportOptions.newTransport(tlsConfig)
 	fz.client = &http.Client{Timeout: time.Duration(fz.Timeout) * time.Second, Transport: tr}
 }
 
@@ -113,22 +191,38 @@ func (fz *FzAPI) ParseXMLResp(r *http.Response) error {
 	defer r.Body.Close()
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		return fmt.Errorf("ParseXMLResp - ReadAll Error: %v", err)
+		return fmt.Errorf("ParseXMLResp - ReadAll Error: %w", &netError{err: err})
 	}
 	fzResp := &XMLFileZen{}
-	err = xml.Unmarshal(body, fzResp)
-	if err != nil {
-		return fmt.Errorf("ParseXMLResp - Unmarshal Error: %v", err)
+	if err := xml.Unmarshal(body, fzResp); err != nil {
+		if r.StatusCode != http.StatusOK || isRedirected(r) {
+			return newFzError(r, "", body)
+		}
+		return fmt.Errorf("ParseXMLResp - Unmarshal Error: %w", err)
+	}
+	setLogRes(r, fzResp.Res)
+	if fzResp.Res != "OK" {
+		return newFzError(r, fzResp.Res, body)
 	}
-	if fzResp.Res == "OK" {
-		fz.LastResp = fzResp
+	fz.mu.Lock()
+	fz.LastResp = fzResp
+	fz.mu.Unlock()
+	return nil
+}
+
+// checkStatus : XML以外の応答のステータスコードを確認する
+func checkStatus(r *http.Response) error {
+	if r.StatusCode == http.StatusOK {
 		return nil
 	}
-	return errors.New(fzResp.Res)
+	body, _ := ioutil.ReadAll(io.LimitReader(r.Body, 64*1024))
+	return newFzError(r, "", body)
 }
 
 // getSessionID : セッションIDを取り出す
 func (fz *FzAPI) getSessionID(r *http.Response) {
+	fz.mu.Lock()
+	defer fz.mu.Unlock()
 	for _, c := range r.Cookies() {
 		if c.Name == "SessionID" {
 			fz.FzSession = *c
@@ -138,35 +232,70 @@ func (fz *FzAPI) getSessionID(r *http.Response) {
 	fz.FzSession = http.Cookie{Name: "SessionID", Value: ""}
 }
 
+// newFzRequest : コンテキスト付きのリクエストを作成する
+func (fz *FzAPI) newFzRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	ua := fz.UserAgent
+	if ua == "" {
+		ua = FileZenRAUserAgent
+	}
+	req.Header.Set("User-Agent", ua)
+	return req, nil
+}
+
 // FzSendPostReq : FileZenはPOSTリクエストを送信する
 func (fz *FzAPI) FzSendPostReq(url string, body io.Reader, bSetCookie bool) (*http.Response, error) {
-	if fz.client == nil {
-		fz.getHTTPClient()
+	return fz.FzSendPostReqContext(context.Background(), url, body, bSetCookie)
+}
+
+// FzSendPostReqContext : コンテキストを指定してFileZenへPOSTリクエストを送信する
+func (fz *FzAPI) FzSendPostReqContext(ctx context.Context, url string, body io.Reader, bSetCookie bool) (*http.Response, error) {
+	req, err := fz.newFzRequest(ctx, "POST", url, body)
+	if err != nil {
+		return nil, err
 	}
-	req, _ := http.NewRequest("POST", url, body)
-	req.Header.Set("User-Agent", FileZenRAUserAgent)
 	if bSetCookie {
-		req.AddCookie(&fz.FzSession)
+		fz.addSession(req)
 	}
-	return fz.client.Do(req)
+	return fz.doRequest(req)
+}
+
+// doRequest : リクエストを送信する
+// 通信エラーは、ErrNetworkで判定できるようにする（Retryに従って再試行する）
+func (fz *FzAPI) doRequest(req *http.Request) (*http.Response, error) {
+	return fz.doRequestWithRetry(fz.httpClient(), req)
 }
 
 // FzLogin : FileZenへログインする
+// fzURLが空の場合は、NewClientなどで設定済みのURLを使用する
 func (fz *FzAPI) FzLogin(fzURL, uid, password string) error {
-	fz.URL = fzURL
+	return fz.FzLoginContext(context.Background(), fzURL, uid, password)
+}
+
+// FzLoginContext : コンテキストを指定してFileZenへログインする
+func (fz *FzAPI) FzLoginContext(ctx context.Context, fzURL, uid, password string) error {
+	ctx = withIdempotent(withFzOp(ctx, "Login", "auth"))
+	if fzURL != "" {
+		fz.mu.Lock()
+		fz.URL = fzURL
+		fz.mu.Unlock()
+	}
 	v := url.Values{}
 	v.Set("respmode", "xml")
 	v.Set("action", "Login")
 	v.Set("sub_action", "auth")
 	v.Set("user_id", uid)
 	v.Set("password", password)
-	resp, err := fz.FzSendPostReq(fz.URL+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), false)
+	resp, err := fz.FzSendPostReqContext(ctx, fz.baseURL()+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), false)
 	if err != nil {
-		return fmt.Errorf("FzLogin - FzSendPostReq Error: %v", err)
+		return fmt.Errorf("FzLogin - FzSendPostReq Error: %w", err)
 	}
 	err = fz.ParseXMLResp(resp)
 	if err != nil {
-		return fmt.Errorf("FzLogin - ParseXmlResp Error: %v", err)
+		return fmt.Errorf("FzLogin - ParseXmlResp Error: %w", err)
 	}
 	fz.getSessionID(resp)
 	return nil
@@ -174,143 +303,211 @@ func (fz *FzAPI) FzLogin(fzURL, uid, password string) error {
 
 // FzLogout : FileZenからログアウトする
 func (fz *FzAPI) FzLogout() error {
+	return fz.FzLogoutContext(context.Background())
+}
+
+// FzLogoutContext : コンテキストを指定してFileZenからログアウトする
+func (fz *FzAPI) FzLogoutContext(ctx context.Context) error {
+	ctx = withIdempotent(withFzOp(ctx, "Logout", "show"))
 	v := url.Values{}
 	v.Set("respmode", "xml")
 	v.Set("action", "Logout")
 	v.Set("sub_action", "show")
-	_, err := fz.FzSendPostReq(fz.URL+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), true)
+	resp, err := fz.FzSendPostReqContext(ctx, fz.baseURL()+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), true)
 	if err != nil {
-		return fmt.Errorf("FzLogout - FzSendPostReq Error: %v", err)
+		return fmt.Errorf("FzLogout - FzSendPostReq Error: %w", err)
 	}
+	resp.Body.Close()
 	return nil
 }
 
 // FzReload : FielZenのログイン情報を更新する（ファイルやフォルダなどのリストの更新）
 func (fz *FzAPI) FzReload() error {
+	return fz.FzReloadContext(context.Background())
+}
+
+// FzReloadContext : コンテキストを指定してFileZenのログイン情報を更新する
+func (fz *FzAPI) FzReloadContext(ctx context.Context) error {
+	return fz.withRelogin(ctx, true, func() error {
+		return fz.fzReload(ctx)
+	})
+}
+
+// fzReload : FzReloadContextの処理
+func (fz *FzAPI) fzReload(ctx context.Context) error {
+	ctx = withIdempotent(withFzOp(ctx, "Mainmenu_file", "show"))
 	v := url.Values{}
 	v.Set("respmode", "xml")
 	v.Set("action", "Mainmenu_file")
 	v.Set("sub_action", "show")
-	v.Set("valid_key", fz.LastResp.ValidKey)
-	resp, err := fz.FzSendPostReq(fz.URL+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), true)
+	v.Set("valid_key", fz.validKey())
+	resp, err := fz.FzSendPostReqContext(ctx, fz.baseURL()+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), true)
 	if err != nil {
-		return fmt.Errorf("FzReload - FzSendPostReq Error: %v", err)
+		return fmt.Errorf("FzReload - FzSendPostReq Error: %w", err)
 	}
 	return fz.ParseXMLResp(resp)
 }
 
 // FzDeleteFile : FileZenのプロジェクト上のファイルを削除する
 func (fz *FzAPI) FzDeleteFile(key string) error {
+	return fz.FzDeleteFileContext(context.Background(), key)
+}
+
+// FzDeleteFileContext : コンテキストを指定してFileZenのファイルを削除する
+func (fz *FzAPI) FzDeleteFileContext(ctx context.Context, key string) error {
+	return fz.withRelogin(ctx, true, func() error {
+		return fz.fzDeleteFile(ctx, key)
+	})
+}
+
+// fzDeleteFile : FzDeleteFileContextの処理
+func (fz *FzAPI) fzDeleteFile(ctx context.Context, key string) error {
+	ctx = withIdempotent(withFzOp(ctx, "Mainmenu_file", "delete_file"))
 	v := url.Values{}
 	v.Set("respmode", "xml")
 	v.Set("action", "Mainmenu_file")
 	v.Set("sub_action", "delete_file")
 	v.Set("key", key)
-	v.Set("valid_key", fz.LastResp.ValidKey)
-	resp, err := fz.FzSendPostReq(fz.URL+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), true)
+	v.Set("valid_key", fz.validKey())
+	resp, err := fz.FzSendPostReqContext(ctx, fz.baseURL()+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), true)
 	if err != nil {
-		return fmt.Errorf("FzDeleteFile - FzSendPostReq Error: %v", err)
+		return fmt.Errorf("FzDeleteFile - FzSendPostReq Error: %w", err)
 	}
 	return fz.ParseXMLResp(resp)
 }
 
 // FzFindFile : FileZenの指定のフォルダ（プロジェクト）内のファイルを探す
+// 見つからない場合は空文字を返す。エラーを判定する場合はFindFileを使用する
 func (fz *FzAPI) FzFindFile(prj string, folder string, file string) string {
-	for _, p := range fz.LastResp.ProjectList {
-		if p.Name == prj {
-			for _, d := range p.FolderList {
-				if d.Name == folder {
-					for _, f := range d.FileList {
-						if f.Name == file {
-							return f.Key
-						}
-					}
-				}
-			}
-		}
-	}
-	return ""
-}
-
-// sepPrjFolder : プロジェクトとフォルダ名を分離する
-func sepPrjFolder(prjFolder string) (string, string) {
-	a := strings.Split(prjFolder, "/")
-	if len(a) < 2 {
-		return prjFolder, ""
+	f, err := fz.FindFile(FzPath{Project: prj, Folders: []string{folder}}, file)
+	if err != nil {
+		return ""
 	}
-	return a[0], a[1]
+	return f.Key
 }
 
-// FzFindFolder : FileZenのフォルダを名前から探す
+// FzFindFolder : FileZenのフォルダを名前（ParseFzPathの形式）から探す
+// 見つからない場合は空のXMLFolderを返す。エラーを判定する場合はFindFolderを使用する
+// 以前は「プロジェクト/フォルダ」より後ろを無視していたが、サブフォルダとして探すようになった。
+// 名前の「\」はエスケープとして扱うため、誤ったエスケープを含む場合は見つからない
 func (fz *FzAPI) FzFindFolder(prjFolder string) *XMLFolder {
-	prj, folder := sepPrjFolder(prjFolder)
-	for _, p := range fz.LastResp.ProjectList {
-		if p.Name == prj {
-			for _, d := range p.FolderList {
-				if d.Name == folder {
-					return d
-				}
-			}
-		}
+	p, err := ParseFzPath(prjFolder)
+	if err != nil {
+		return &XMLFolder{}
+	}
+	d, err := fz.FindFolder(p)
+	if err != nil {
+		return &XMLFolder{}
 	}
-	return &XMLFolder{}
+	return d
 }
 
 // CanUpload : FileZenへファイルをアップロード可能かどうか判断する
+// 理由を判定する場合はCanUploadToを使用する
 func (fz *FzAPI) CanUpload(prjFolder string, file string) bool {
-	prj, folder := sepPrjFolder(prjFolder)
-	ret := false
-	for _, p := range fz.LastResp.ProjectList {
-		if p.Name == prj {
-			for _, d := range p.FolderList {
-				if d.Name == folder {
-					for _, f := range d.FileList {
-						if f.Name == file {
-							return false
-						}
-					}
-					// アクセス可能なFolderがある場合
-					if strings.Index(d.Access, "write") != -1 {
-						ret = true
-					}
-				}
-			}
-		}
+	p, err := ParseFzPath(prjFolder)
+	if err != nil {
+		return false
 	}
-	return ret
+	ok, err := fz.CanUploadTo(p, file)
+	return ok && err == nil
 }
 
 // FzDownload : FileZenからファイルをダウンロードする
 func (fz *FzAPI) FzDownload(key string, localFile string) error {
+	return fz.FzDownloadContext(context.Background(), key, localFile)
+}
+
+// FzDownloadContext : コンテキストを指定してFileZenからファイルをダウンロードする
+// 途中でキャンセルされた場合など、エラー時には作成途中のファイルを削除する
+func (fz *FzAPI) FzDownloadContext(ctx context.Context, key string, localFile string) error {
+	return fz.withRelogin(ctx, true, func() error {
+		return fz.fzDownload(ctx, key, localFile)
+	})
+}
+
+// fzDownload : FzDownloadContextの処理
+func (fz *FzAPI) fzDownload(ctx context.Context, key string, localFile string) error {
+	output := &outputFile{path: localFile}
+	ctx = withProgressName(ctx, filepath.Base(localFile))
+	return output.finish(fz.fzDownloadWriter(ctx, key, output))
+}
+
+// FzDownloadWriter : FileZenからダウンロードしたファイルの内容をWriterに書き込む
+func (fz *FzAPI) FzDownloadWriter(ctx context.Context, key string, w io.Writer) error {
+	return fz.withRelogin(ctx, true, func() error {
+		return fz.fzDownloadWriter(ctx, key, w)
+	})
+}
+
+// fzDownloadWriter : FzDownloadWriterの処理
+func (fz *FzAPI) fzDownloadWriter(ctx context.Context, key string, w io.Writer) error {
+	ctx = withIdempotent(withFzOp(ctx, "Mainmenu_file", "download"))
 	v := url.Values{}
 	v.Set("respmode", "xml")
 	v.Set("action", "Mainmenu_file")
 	v.Set("sub_action", "download")
 	v.Set("key", key)
-	v.Set("valid_key", fz.LastResp.ValidKey)
-	resp, err := fz.FzSendPostReq(fz.URL+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), true)
+	v.Set("valid_key", fz.validKey())
+	resp, err := fz.FzSendPostReqContext(ctx, fz.baseURL()+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), true)
 	if err != nil {
-		return fmt.Errorf("FzDownload - FzSendPostReq Error: %v", err)
+		return fmt.Errorf("FzDownload - FzSendPostReq Error: %w", err)
 	}
 	defer resp.Body.Close()
 	if resp.ContentLength < 0 {
 		err = fz.ParseXMLResp(resp)
 		if err != nil {
-			return fmt.Errorf("FzDownload - ParseXmlResp Error: %v", err)
+			return fmt.Errorf("FzDownload - ParseXmlResp Error: %w", err)
 		}
 	}
-	output, err := os.Create(localFile)
-	if err != nil {
-		return fmt.Errorf("FzDownload - os.Create Error: %v", err)
+	if err := checkStatus(resp); err != nil {
+		return fmt.Errorf("FzDownload - Status Error: %w", err)
 	}
-	defer output.Close()
-	_, err = io.Copy(output, resp.Body)
+	pr := fz.newProgress(ctx, key, resp.ContentLength)
+	defer pr.finish()
+	_, err = io.Copy(pr.writer(fz.newThrottle(ctx).writer(w)), resp.Body)
 	if err != nil {
-		return fmt.Errorf("FzDownload - io.Copy Error: %v", err)
+		return fmt.Errorf("FzDownload - io.Copy Error: %w", err)
 	}
 	return nil
 }
 
+// outputFile : 最初の書き込みで作成するファイル
+// 応答がエラーの場合に、既存のファイルを壊さないようにする
+type outputFile struct {
+	path string
+	f    *os.File
+}
+
+// Write : ファイルに書き込む
+func (o *outputFile) Write(p []byte) (int, error) {
+	if o.f == nil {
+		f, err := os.Create(o.path)
+		if err != nil {
+			return 0, err
+		}
+		o.f = f
+	}
+	return o.f.Write(p)
+}
+
+// finish : ファイルを閉じる。エラーの場合は、作成途中のファイルを削除する
+func (o *outputFile) finish(err error) error {
+	if err == nil && o.f == nil {
+		_, err = o.Write(nil)
+	}
+	if o.f == nil {
+		return err
+	}
+	if cerr := o.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(o.path)
+	}
+	return err
+}
+
 // secureRandam : 分割アップロードに使用する乱数キーを作成する
 func secureRandam(c int) string {
 	rand.Seed(time.Now().UnixNano())
@@ -319,169 +516,150 @@ func secureRandam(c int) string {
 
 // fzPlUploadPart : FileZenへリクエストを分割してファイルをアップロードする場合の
 // １ファイルのアップロード処理
-func (fz *FzAPI) fzPlUploadPart(src io.Reader, nSize int64, fr, folderID string, chunk, chunks int) (bool, error) {
-	writer := multipart.NewWriter(gBody)
-	part, err := writer.CreateFormFile("file", fr+folderID+".tmp")
-	if err != nil {
-		return false, fmt.Errorf("FzPlUploadPart - CreateFormFile Error: %v", err)
-	}
-	_, err = io.CopyN(part, src, nSize)
-	_ = writer.WriteField("fr", fr)
-	_ = writer.WriteField("valid_key", fz.LastResp.ValidKey)
-	_ = writer.WriteField("action", "Mainmenu_upload")
-	_ = writer.WriteField("sub_action", "plupload")
-	_ = writer.WriteField("ukey", fz.FzSession.Value+folderID)
-	_ = writer.WriteField("mode", "PRJ")
-	_ = writer.WriteField("chunk", strconv.Itoa(chunk))
-	_ = writer.WriteField("chunks", strconv.Itoa(chunks))
-	err = writer.Close()
-	if err != nil {
-		return false, fmt.Errorf("FzPlUploadPart - writer.Close Error: %v", err)
+func (fz *FzAPI) fzPlUploadPart(ctx context.Context, src io.Reader, nSize int64, fr, folderID string, chunk, chunks int) (bool, error) {
+	ctx = withFzOp(ctx, "Mainmenu_upload", "plupload")
+	files := []formFile{{key: "file", name: fr + folderID + ".tmp", r: io.LimitReader(src, nSize), size: nSize}}
+	fields := formFields{}
+	fields.add("fr", fr)
+	fields.add("valid_key", fz.validKey())
+	fields.add("action", "Mainmenu_upload")
+	fields.add("sub_action", "plupload")
+	fields.add("ukey", fz.session().Value+folderID)
+	fields.add("mode", "PRJ")
+	fields.add("chunk", strconv.Itoa(chunk))
+	fields.add("chunks", strconv.Itoa(chunks))
+	req, err := fz.newMultipartRequest(ctx, fz.baseURL()+"/cgi-bin/index.cgi", files, fields)
+	if err != nil {
+		return false, fmt.Errorf("FzPlUploadPart - NewRequest Error: %w", err)
+	}
+	fz.addSession(req)
+	resp, err := fz.doRequest(req)
+	if err != nil {
+		return false, fmt.Errorf("FzPlUploadPart - POST Error: %w", err)
 	}
-	req, _ := http.NewRequest("POST", fz.URL+"/cgi-bin/index.cgi", gBody)
-	req.Header.Set("User-Agent", FileZenRAUserAgent)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.AddCookie(&fz.FzSession)
-	resp, err := fz.client.Do(req)
-	if err != nil {
-		return false, fmt.Errorf("FzPlUploadPart - POST Error: %v", err)
+	defer resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return false, fmt.Errorf("FzPlUploadPart - Status Error: %w", err)
 	}
-	err = resp.Body.Close()
+	_, err = io.Copy(ioutil.Discard, resp.Body)
 	if err != nil {
-		return false, fmt.Errorf("FzPlUploadPart - resp.Body.Close Error: %v", err)
+		return false, fmt.Errorf("FzPlUploadPart - Read Body Error: %w", err)
 	}
 	return true, nil
 }
 
 // FzPlUpload : FileZenへファイルを分割したリクエストでアップロードする
 func (fz *FzAPI) FzPlUpload(localFile, folderID, regName, comment, notifyTo, notifyMode string) error {
+	return fz.FzPlUploadContext(context.Background(), localFile, folderID, regName, comment, notifyTo, notifyMode)
+}
+
+// FzPlUploadContext : コンテキストを指定してFileZenへファイルを分割アップロードする
+// キャンセルされた場合は、残りの分割アップロードを中止する
+func (fz *FzAPI) FzPlUploadContext(ctx context.Context, localFile, folderID, regName, comment, notifyTo, notifyMode string) error {
+	return fz.FzPlUploadWithOptions(ctx, localFile, folderID, NewUploadOptions(regName, comment, notifyTo, notifyMode))
+}
+
+// fzPlUpload : FzPlUploadContextの処理
+func (fz *FzAPI) fzPlUpload(ctx context.Context, localFile, folderID string, opts UploadOptions) error {
 	fstat, err := os.Stat(localFile)
 	if err != nil {
-		return fmt.Errorf("FzPlUpload - os.stat Error: %v", err)
+		return fmt.Errorf("FzPlUpload - os.stat Error: %w", err)
 	}
 	file, err := os.Open(localFile)
 	if err != nil {
-		return fmt.Errorf("FzPlUpload - os.Open Error: %v", err)
+		return fmt.Errorf("FzPlUpload - os.Open Error: %w", err)
 	}
 	defer file.Close()
-	fr := secureRandam(10)
-	nTotalSize := fstat.Size()
-	var n int64
-	var nSend int64
-	chunk := 1
-	nChunkSize := int64(1024 * 1024 * 50) // 50MB
-	chunks := int(nTotalSize / nChunkSize)
-	if nTotalSize%nChunkSize != 0 {
-		chunks++
-	}
-	for n = 0; n < nTotalSize; {
-		nSend = nChunkSize
-		if nTotalSize-n < nChunkSize {
-			nSend = nTotalSize - n
-		}
-		_, err = fz.fzPlUploadPart(file, nSend, fr, folderID, chunk, chunks)
-		if err != nil {
-			return err
-		}
-		n += nSend
-		chunk++
+	st := fz.newPlUploadState(filepath.Base(localFile), fstat.Size(), folderID)
+	return fz.plUpload(ctx, file, file, st, "", opts)
+}
+
+// FzPlUploadReader : Readerの内容を分割したリクエストでFileZenへアップロードする
+// nameはFileZen上のファイル名、sizeはReaderから読み込むサイズ（必須）
+func (fz *FzAPI) FzPlUploadReader(ctx context.Context, r io.Reader, name string, size int64, folderID, regName, comment, notifyTo, notifyMode string) error {
+	return fz.FzPlUploadReaderWithOptions(ctx, r, name, size, folderID, NewUploadOptions(regName, comment, notifyTo, notifyMode))
+}
+
+// fzPlUploadReader : FzPlUploadReaderの処理
+func (fz *FzAPI) fzPlUploadReader(ctx context.Context, file io.Reader, name string, size int64, folderID string, opts UploadOptions) error {
+	if size < 0 {
+		return fmt.Errorf("FzPlUpload - size unknown: %w", ErrInvalidParam)
 	}
+	return fz.plUpload(ctx, file, nil, fz.newPlUploadState(name, size, folderID), "", opts)
+}
+
+// fzPlUploadDone : 分割アップロードしたファイルを登録する
+func (fz *FzAPI) fzPlUploadDone(ctx context.Context, fr, name, folderID string, opts UploadOptions) error {
+	ctx = withFzOp(ctx, "Mainmenu_upload", "do_upload")
 	v := url.Values{}
 	v.Set("respmode", "xml")
 	v.Set("action", "Mainmenu_upload")
 	v.Set("sub_action", "do_upload")
-	v.Set("valid_key", fz.LastResp.ValidKey)
-	v.Set("filename", filepath.Base(localFile))
+	v.Set("valid_key", fz.validKey())
+	v.Set("filename", name)
 	v.Set("ST_current_folder", folderID)
-	v.Set("reg_filename", regName)
-	v.Set("description", comment)
 	v.Set("fr", fr)
 	v.Set("key", "")
-	if strings.Index(notifyTo, "ALL") != -1 {
-		v.Set("mail_send", "1")
-	} else if notifyTo == "" {
-		v.Set("mail_send", "0")
-	} else {
-		v.Set("mail_send", "2")
-	}
-	if strings.Index(notifyMode, "DOWNLOAD") != -1 {
-		v.Set("notify_download", "1")
-	} else {
-		v.Set("notify_download", "0")
-	}
-	if strings.Index(notifyMode, "ALTER") != -1 {
-		v.Set("notify_alter", "1")
-	} else {
-		v.Set("notify_alter", "0")
-	}
-	if strings.Index(notifyMode, "DELETE") != -1 {
-		v.Set("notify_delete", "1")
-	} else {
-		v.Set("notify_delete", "0")
-	}
-	v.Set("new_alert", "1")
-	resp, err := fz.FzSendPostReq(fz.URL+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), true)
+	opts.addFields(v.Add)
+	resp, err := fz.FzSendPostReqContext(ctx, fz.baseURL()+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), true)
 	if err != nil {
-		return fmt.Errorf("FzPlUpload - FzSendPostReq Error: %v", err)
+		return fmt.Errorf("FzPlUpload - FzSendPostReq Error: %w", err)
 	}
 	return fz.ParseXMLResp(resp)
 }
 
 // FzUpload : FileZenへ１つのリクエストでファイルをアップロードする(HTMLモードと同じ)
 func (fz *FzAPI) FzUpload(localFile, folderID, regName, comment, notifyTo, notifyMode string) error {
-	file, err := os.Open(localFile)
-	if err != nil {
-		return fmt.Errorf("FzUpload - os.Open Error: %v", err)
-	}
-	defer file.Close()
-	writer := multipart.NewWriter(gBody)
-	part, err := writer.CreateFormFile("filename", filepath.Base(localFile))
+	return fz.FzUploadContext(context.Background(), localFile, folderID, regName, comment, notifyTo, notifyMode)
+}
+
+// FzUploadContext : コンテキストを指定してFileZenへ１つのリクエストでファイルをアップロードする
+func (fz *FzAPI) FzUploadContext(ctx context.Context, localFile, folderID, regName, comment, notifyTo, notifyMode string) error {
+	return fz.FzUploadWithOptions(ctx, localFile, folderID, NewUploadOptions(regName, comment, notifyTo, notifyMode))
+}
+
+// fzUpload : FzUploadContextの処理
+func (fz *FzAPI) fzUpload(ctx context.Context, localFile, folderID string, opts UploadOptions) error {
+	fstat, err := os.Stat(localFile)
 	if err != nil {
-		return fmt.Errorf("FzUpload - writer.CreateFormFile Error: %v", err)
-	}
-	_, err = io.Copy(part, file)
-	_ = writer.WriteField("action", "Mainmenu_upload")
-	_ = writer.WriteField("sub_action", "do_upload")
-	_ = writer.WriteField("respmode", "xml")
-	_ = writer.WriteField("valid_key", fz.LastResp.ValidKey)
-	_ = writer.WriteField("ST_current_folder", folderID)
-	_ = writer.WriteField("reg_filename", regName)
-	_ = writer.WriteField("description", comment)
-	_ = writer.WriteField("key", "")
-	if strings.Index(notifyTo, "ALL") != -1 {
-		_ = writer.WriteField("mail_send", "1")
-	} else if notifyTo == "" {
-		_ = writer.WriteField("mail_send", "0")
-	} else {
-		_ = writer.WriteField("mail_send", "2")
-	}
-	if strings.Index(notifyMode, "DOWNLOAD") != -1 {
-		_ = writer.WriteField("notify_download", "1")
-	} else {
-		_ = writer.WriteField("notify_download", "0")
-	}
-	if strings.Index(notifyMode, "ALTER") != -1 {
-		_ = writer.WriteField("notify_alter", "1")
-	} else {
-		_ = writer.WriteField("notify_alter", "0")
+		return fmt.Errorf("FzUpload - os.Stat Error: %w", err)
 	}
-	if strings.Index(notifyMode, "DELETE") != -1 {
-		_ = writer.WriteField("notify_delete", "1")
-	} else {
-		_ = writer.WriteField("notify_delete", "0")
-	}
-	_ = writer.WriteField("new_alert", "1")
-	err = writer.Close()
+	file, err := os.Open(localFile)
 	if err != nil {
-		return fmt.Errorf("FzUpload - writer.Close Error: %v", err)
+		return fmt.Errorf("FzUpload - os.Open Error: %w", err)
 	}
-	req, _ := http.NewRequest("POST", fz.URL+"/cgi-bin/index.cgi", gBody)
-	req.Header.Set("User-Agent", FileZenRAUserAgent)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.AddCookie(&fz.FzSession)
-	resp, err := fz.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("FzUpload - POST Error: %v", err)
+	defer file.Close()
+	return fz.fzUploadReader(ctx, file, filepath.Base(localFile), fstat.Size(), folderID, opts)
+}
+
+// FzUploadReader : Readerの内容を１つのリクエストでFileZenへアップロードする
+// nameはFileZen上のファイル名、sizeが不明な場合は-1を指定する
+func (fz *FzAPI) FzUploadReader(ctx context.Context, r io.Reader, name string, size int64, folderID, regName, comment, notifyTo, notifyMode string) error {
+	return fz.FzUploadReaderWithOptions(ctx, r, name, size, folderID, NewUploadOptions(regName, comment, notifyTo, notifyMode))
+}
+
+// fzUploadReader : FzUploadReaderの処理
+func (fz *FzAPI) fzUploadReader(ctx context.Context, r io.Reader, name string, size int64, folderID string, opts UploadOptions) error {
+	ctx = withFzOp(ctx, "Mainmenu_upload", "do_upload")
+	pr := fz.newProgress(ctx, name, size)
+	defer pr.finish()
+	files := []formFile{{key: "filename", name: name, r: pr.reader(fz.newThrottle(ctx).reader(r)), size: size}}
+	fields := formFields{}
+	fields.add("action", "Mainmenu_upload")
+	fields.add("sub_action", "do_upload")
+	fields.add("respmode", "xml")
+	fields.add("valid_key", fz.validKey())
+	fields.add("ST_current_folder", folderID)
+	fields.add("key", "")
+	opts.addFields(fields.add)
+	req, err := fz.newMultipartRequest(ctx, fz.baseURL()+"/cgi-bin/index.cgi", files, fields)
+	if err != nil {
+		return fmt.Errorf("FzUpload - NewRequest Error: %w", err)
+	}
+	fz.addSession(req)
+	resp, err := fz.doRequest(req)
+	if err != nil {
+		return fmt.Errorf("FzUpload - POST Error: %w", err)
 	}
 	return fz.ParseXMLResp(resp)
 }
@@ -523,15 +701,6 @@ type eMailAddr struct {
 	Email string
 }
 
-// getMbOpt: めるあど便のオプションを取得する
-func getMbOpt(mb map[string]string, k string, d string) string {
-	v, ok := mb[k]
-	if ok {
-		return v
-	}
-	return d
-}
-
 // getEMailAddr: めるあど便の設定ファイルからメールアドレスを取得する
 func getEMailAddr(s string) eMailAddr {
 	name := ""
@@ -553,321 +722,324 @@ func getEMailAddr(s string) eMailAddr {
 	return ret
 }
 
-/*
-	loadMbConf : めるあど便の送信ファイルを読み込む
-   subject: 件名
-   mailto: 宛先<email>(複数指定可能)
-   from: 送信元<email>
-   file: ファイル名｜ディレクトリ名
-   start:公開開始日
-   days:公開期間
-   limit: ダウンロード回数制限
-   comment: このキー以降は、本文にする
-*/
-func loadMbConf(mbConf string) (map[string]string, error) {
-	ret := map[string]string{}
+// loadMbConf : めるあど便の送信ファイルを読み込む
+//
+//	subject: 件名
+//	mailto: 宛先<email>(複数指定可能)
+//	from: 送信元<email>
+//	file: ファイル名｜ディレクトリ名(最大5つまで複数指定可能)
+//	start:公開開始日時(2006/01/02 15:04、時刻は省略可能)
+//	days:公開期間
+//	limit: ダウンロード回数制限
+//	password: ダウンロードのパスワード
+//	notify: ダウンロードの通知(true|false)
+//	lang: メールの言語(ambi|ja|en)
+//	split: 上限を超えるファイルの分割(none|attachments|mails)
+//	volume: 分割するサイズ(例: 100M)
+//	comment: このキー以降は、本文にする
+func loadMbConf(mbConf string) (*MailJob, error) {
 	fp, err := os.Open(mbConf)
 	if err != nil {
-		return ret, err
+		return nil, err
 	}
 	defer fp.Close()
+	job := &MailJob{}
+	seen := map[string]bool{}
 	scanner := bufio.NewScanner(fp)
 	bInComment := false
 	comment := ""
-	err = nil
-	line := ""
 	for scanner.Scan() {
-		if err = scanner.Err(); err != nil {
-			break
-		}
-		line = scanner.Text()
+		line := scanner.Text()
 		if bInComment {
 			comment += line + "\n"
 			continue
 		}
-		a := strings.Split(line, ":")
+		a := strings.SplitN(line, ":", 2)
 		if len(a) < 2 {
 			continue
 		}
-		k := strings.ToLower(a[0])
+		k := strings.ToLower(strings.TrimSpace(a[0]))
 		v := strings.TrimSpace(a[1])
 		if k == "comment" {
 			bInComment = true
 			comment = v + "\n"
-		} else if k == "mailto" {
-			_, ok := ret["mailto"]
-			if ok {
-				ret["mailto"] += ","
-				ret["mailto"] += v
-			} else {
-				ret["mailto"] = v
-			}
-		} else {
-			ret[k] = v
+			continue
 		}
+		if err := job.setMbOpt(k, v); err != nil {
+			return nil, err
+		}
+		seen[k] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
 	}
-	ret["comment"] = comment
-	return ret, nil
+	if err := checkMbOpts(func(k string) bool { return seen[k] }); err != nil {
+		return nil, err
+	}
+	job.Comment = comment
+	return job, nil
 }
 
-// makeZip : ZIPファイルの作成
-func makeZip(f string) (string, error) {
-	zipFile, err := filepath.Abs(f)
-	if err != nil {
-		return "", err
-	}
+// makeZip : ディレクトリからZIPファイルを作成する
+func makeZip(dir, zipFile string) (string, error) {
 	zip := &archivex.ZipFile{}
 	if err := zip.Create(zipFile); err != nil {
 		return "", err
 	}
 	defer zip.Close()
-	if err := zip.AddAll(f, true); err != nil {
+	if err := zip.AddAll(dir, true); err != nil {
 		return "", err
 	}
-	return zipFile, nil
+	return zip.Name, nil
 }
 
 // FzMail : めるあど便の送信
 func (fz *FzAPI) FzMail(mbConf string) error {
-	mb, err := loadMbConf(mbConf)
-	if err != nil {
-		return fmt.Errorf("FzMail loadMbConf err=%v", err)
-	}
-	f, ok := mb["file"]
-	if !ok {
-		return fmt.Errorf("FzMail file not specified")
-	}
-	fstat, err := os.Stat(f)
+	return fz.FzMailContext(context.Background(), mbConf)
+}
+
+// FzMailContext : コンテキストを指定してめるあど便を送信する
+func (fz *FzAPI) FzMailContext(ctx context.Context, mbConf string) error {
+	job, err := loadMbConf(mbConf)
 	if err != nil {
-		return fmt.Errorf("FzMail file stat err=%v", err)
-	}
-	if fstat.IsDir() {
-		mb["file"], err = makeZip(f)
-		if err != nil {
-			return fmt.Errorf("FzMail makeZip err=%v", err)
-		}
-		defer os.Remove(mb["file"])
+		return fmt.Errorf("FzMail loadMbConf err=%w", err)
 	}
-	return fz.FzSendMB(mb)
+	return fz.FzSendMailJob(ctx, job)
 }
 
 // FzSendMB : めるあど便を送信する
 func (fz *FzAPI) FzSendMB(mbconf map[string]string) error {
-	from := getMbOpt(mbconf, "from", "")
-	if from == "" {
-		return errors.New("No from")
-	}
-	var toList []eMailAddr
-	for _, to := range strings.Split(getMbOpt(mbconf, "mailto", ""), ",") {
-		aTo := getEMailAddr(to)
-		if aTo.Name != "" {
-			toList = append(toList, aTo)
-		}
-	}
-	if len(toList) < 1 {
-		return errors.New("No mailto")
-	}
-	localFile := getMbOpt(mbconf, "file", "")
-	if localFile == "" {
-		return errors.New("No file")
-	}
-	st, err := time.Parse("2006/01/02", getMbOpt(mbconf, "start", ""))
-	if err != nil {
-		return errors.New("Invalid start date")
-	}
-	days := getMbOpt(mbconf, "days", "HEHEH")
-	nDays, err := strconv.Atoi(days)
-	if err != nil {
-		return errors.New("Invalid days")
-	}
-	limit := getMbOpt(mbconf, "limit", "HEHEH")
-	nLimitCount, err := strconv.Atoi(limit)
-	if err != nil {
-		return errors.New("Invalid limit")
-	}
-	comment := getMbOpt(mbconf, "comment", "")
-	subject := getMbOpt(mbconf, "subject", "")
-	password := getMbOpt(mbconf, "password", "")
-	bNotifyDownload := false
-	if getMbOpt(mbconf, "notify", "false") == "true" {
-		bNotifyDownload = true
-	}
-	fstat, err := os.Stat(localFile)
-	if err != nil {
-		return fmt.Errorf("FzSendMB - os.Stat Error: %v", err)
-	}
-	if fstat.Size() > 1024*1024*200 {
-		return fmt.Errorf("FzSendMB - File Size over 200MB")
-	}
-	file, err := os.Open(localFile)
-	if err != nil {
-		return fmt.Errorf("FzSendMB - os.Open Error: %v", err)
-	}
-	defer file.Close()
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("file1", filepath.Base(localFile))
-	if err != nil {
-		return fmt.Errorf("FzSendMB - writer.CreateFormFile Error: %v", err)
-	}
-	_, err = io.Copy(part, file)
-	_ = writer.WriteField("subject", subject)
-	_ = writer.WriteField("comment", comment)
-	_ = writer.WriteField("exp_term_start_year", fmt.Sprintf("%d", st.Year()))
-	_ = writer.WriteField("exp_term_start_month", fmt.Sprintf("%d", st.Month()))
-	_ = writer.WriteField("exp_term_start_day", fmt.Sprintf("%d", st.Day()))
-	_ = writer.WriteField("exp_term_start_hour", "00")
-	_ = writer.WriteField("exp_term_start_minute", "00")
-	_ = writer.WriteField("exp_term_type", "by_dur")
-	_ = writer.WriteField("exp_term_duration", fmt.Sprintf("%d", nDays))
-	_ = writer.WriteField("download_times", fmt.Sprintf("%d", nLimitCount))
-	_ = writer.WriteField("recipients-max-id", fmt.Sprintf("%d", len(toList)))
-	for i, toEnt := range toList {
-		_ = writer.WriteField(fmt.Sprintf("recipient-name-%d", i+1), toEnt.Name)
-		_ = writer.WriteField(fmt.Sprintf("recipient-email-%d", i+1), toEnt.Email)
-	}
-	_ = writer.WriteField("from_addr", "user")
-	_ = writer.WriteField("from_addr_val", from)
-	_ = writer.WriteField("lang", "ambi")
-	_ = writer.WriteField("password", password)
-	_ = writer.WriteField("password_retype", password)
-	_ = writer.WriteField("file2", "")
-	_ = writer.WriteField("file3", "")
-	_ = writer.WriteField("file4", "")
-	_ = writer.WriteField("file5", "")
-	_ = writer.WriteField("respmode", "xml")
-	_ = writer.WriteField("valid_key", fz.LastResp.ValidKey)
-	_ = writer.WriteField("key", "")
-	if bNotifyDownload {
-		_ = writer.WriteField("notify_download", "1")
-	} else {
-		_ = writer.WriteField("notify_download", "0")
-	}
-	_ = writer.WriteField("new_alert", "1")
-	err = writer.Close()
-	if err != nil {
-		return fmt.Errorf("FzSendMB - writer.Close Error: %v", err)
-	}
-	req, _ := http.NewRequest("POST", fz.URL+"/mb/cgi-bin/index.cgi/job/api_send/", body)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("User-Agent", FileZenRAUserAgent)
-	req.AddCookie(&fz.FzSession)
-	resp, err := fz.client.Do(req)
+	return fz.FzSendMBContext(context.Background(), mbconf)
+}
+
+// FzSendMBContext : コンテキストを指定してめるあど便を送信する
+// 項目はloadMbConfと同じ。以前と同じく不明な項目は無視する
+func (fz *FzAPI) FzSendMBContext(ctx context.Context, mbconf map[string]string) error {
+	job, err := NewMailJob(mbconf)
 	if err != nil {
-		return fmt.Errorf("FzSendMB - POST Error: %v", err)
+		return fmt.Errorf("FzSendMB - NewMailJob Error: %w", err)
 	}
-	return fz.ParseXMLResp(resp)
+	return fz.FzSendMailJob(ctx, job)
 }
 
 // FzExportCSV : FileZenから指定したCSV設定ファイルをダウンロードする
 func (fz *FzAPI) FzExportCSV(params map[string]string, localFile string) error {
-	output, err := os.Create(localFile)
-	if err != nil {
-		return fmt.Errorf("FzExportCSV - os.Create Error: %v", err)
-	}
-	defer output.Close()
+	return fz.FzExportCSVContext(context.Background(), params, localFile)
+}
+
+// FzExportCSVContext : コンテキストを指定してCSV設定ファイルをダウンロードする
+func (fz *FzAPI) FzExportCSVContext(ctx context.Context, params map[string]string, localFile string) error {
+	return fz.withRelogin(ctx, true, func() error {
+		return fz.fzExportCSV(ctx, params, localFile)
+	})
+}
+
+// fzExportCSV : FzExportCSVContextの処理
+func (fz *FzAPI) fzExportCSV(ctx context.Context, params map[string]string, localFile string) error {
+	output := &outputFile{path: localFile}
+	ctx = withProgressName(ctx, filepath.Base(localFile))
+	return output.finish(fz.fzExportCSVWriter(ctx, params, output))
+}
+
+// FzExportCSVWriter : FileZenからエクスポートしたCSVをWriterに書き込む
+func (fz *FzAPI) FzExportCSVWriter(ctx context.Context, params map[string]string, w io.Writer) error {
+	return fz.withRelogin(ctx, true, func() error {
+		return fz.fzExportCSVWriter(ctx, params, w)
+	})
+}
+
+// fzExportCSVWriter : FzExportCSVWriterの処理
+func (fz *FzAPI) fzExportCSVWriter(ctx context.Context, params map[string]string, w io.Writer) error {
+	ctx = withIdempotent(withFzOp(ctx, params["action"], params["sub_action"]))
 	v := url.Values{}
 	v.Set("respmode", "csv")
 	for key, val := range params {
 		v.Set(key, val)
 	}
-	v.Set("valid_key", fz.LastResp.ValidKey)
-	resp, err := fz.FzSendPostReq(fz.URL+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), true)
+	v.Set("valid_key", fz.validKey())
+	resp, err := fz.FzSendPostReqContext(ctx, fz.baseURL()+"/cgi-bin/index.cgi", strings.NewReader(v.Encode()), true)
 	if err != nil {
-		return fmt.Errorf("FzExportCSV - FzSendPostReq Error: %v", err)
+		return fmt.Errorf("FzExportCSV - FzSendPostReq Error: %w", err)
 	}
 	defer resp.Body.Close()
-	_, err = io.Copy(output, resp.Body)
+	if err := checkStatus(resp); err != nil {
+		return fmt.Errorf("FzExportCSV - Status Error: %w", err)
+	}
+	pr := fz.newProgress(ctx, params["action"], resp.ContentLength)
+	defer pr.finish()
+	_, err = io.Copy(pr.writer(w), resp.Body)
 	if err != nil {
-		return fmt.Errorf("FzExportCSV - io.Copy Error: %v", err)
+		return fmt.Errorf("FzExportCSV - io.Copy Error: %w", err)
 	}
 	return nil
 }
 
 // FzImportCSV : FileZenへCSV設定ファイルをアップロードする（インポート）
 func (fz *FzAPI) FzImportCSV(params map[string]string, localFile, fileKey string) error {
-	file, err := os.Open(localFile)
+	return fz.FzImportCSVContext(context.Background(), params, localFile, fileKey)
+}
+
+// FzImportCSVContext : コンテキストを指定してCSV設定ファイルをインポートする
+func (fz *FzAPI) FzImportCSVContext(ctx context.Context, params map[string]string, localFile, fileKey string) error {
+	return fz.withRelogin(ctx, false, func() error {
+		return fz.fzImportCSV(ctx, params, localFile, fileKey)
+	})
+}
+
+// fzImportCSV : FzImportCSVContextの処理
+func (fz *FzAPI) fzImportCSV(ctx context.Context, params map[string]string, localFile, fileKey string) error {
+	fstat, err := os.Stat(localFile)
 	if err != nil {
-		return fmt.Errorf("FzImportCSV - os.Open Error: %v", err)
+		return fmt.Errorf("FzImportCSV - os.Stat Error: %w", err)
 	}
-	defer file.Close()
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile(fileKey, filepath.Base(localFile))
+	file, err := os.Open(localFile)
 	if err != nil {
-		return fmt.Errorf("FzImportCSV - writer.CreateFormFile Error: %v", err)
+		return fmt.Errorf("FzImportCSV - os.Open Error: %w", err)
 	}
-	_, err = io.Copy(part, file)
+	defer file.Close()
+	return fz.fzImportCSVReader(ctx, params, file, filepath.Base(localFile), fstat.Size(), fileKey)
+}
+
+// FzImportCSVReader : Readerの内容をCSV設定ファイルとしてFileZenへインポートする
+// nameはCSVのファイル名、sizeが不明な場合は-1を指定する
+func (fz *FzAPI) FzImportCSVReader(ctx context.Context, params map[string]string, r io.Reader, name string, size int64, fileKey string) error {
+	return fz.withRelogin(ctx, false, func() error {
+		return fz.fzImportCSVReader(ctx, params, r, name, size, fileKey)
+	})
+}
+
+// fzImportCSVReader : FzImportCSVReaderの処理
+func (fz *FzAPI) fzImportCSVReader(ctx context.Context, params map[string]string, r io.Reader, name string, size int64, fileKey string) error {
+	ctx = withFzOp(ctx, params["action"], params["sub_action"])
+	pr := fz.newProgress(ctx, name, size)
+	defer pr.finish()
+	files := []formFile{{key: fileKey, name: name, r: pr.reader(r), size: size}}
+	fields := formFields{}
 	for key, val := range params {
-		_ = writer.WriteField(key, val)
+		fields.add(key, val)
 	}
-	_ = writer.WriteField("respmode", "xml")
-	_ = writer.WriteField("valid_key", fz.LastResp.ValidKey)
-	err = writer.Close()
+	fields.add("respmode", "xml")
+	fields.add("valid_key", fz.validKey())
+	req, err := fz.newMultipartRequest(ctx, fz.baseURL()+"/cgi-bin/index.cgi", files, fields)
 	if err != nil {
-		return fmt.Errorf("FzImportCSV - writer.Close Error: %v", err)
+		return fmt.Errorf("FzImportCSV - NewRequest Error: %w", err)
 	}
-	req, _ := http.NewRequest("POST", fz.URL+"/cgi-bin/index.cgi", body)
-	req.Header.Set("User-Agent", FileZenRAUserAgent)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.AddCookie(&fz.FzSession)
-	resp, err := fz.client.Do(req)
+	fz.addSession(req)
+	resp, err := fz.doRequest(req)
 	if err != nil {
-		return fmt.Errorf("FzImportCSV - Post Error: %v", err)
+		return fmt.Errorf("FzImportCSV - Post Error: %w", err)
 	}
 	return fz.ParseXMLResp(resp)
 }
 
 // MbLogExport : めるあど便の履歴をダウンロードする
 func (fz *FzAPI) MbLogExport(params map[string]string, localFile string) error {
-	output, err := os.Create(localFile)
-	if err != nil {
-		return fmt.Errorf("MbLogExport - os.Create Error: %v", err)
-	}
-	defer output.Close()
+	return fz.MbLogExportContext(context.Background(), params, localFile)
+}
+
+// MbLogExportContext : コンテキストを指定してめるあど便の履歴をダウンロードする
+func (fz *FzAPI) MbLogExportContext(ctx context.Context, params map[string]string, localFile string) error {
+	return fz.withRelogin(ctx, true, func() error {
+		return fz.mbLogExport(ctx, params, localFile)
+	})
+}
+
+// mbLogExport : MbLogExportContextの処理
+func (fz *FzAPI) mbLogExport(ctx context.Context, params map[string]string, localFile string) error {
+	output := &outputFile{path: localFile}
+	ctx = withProgressName(ctx, filepath.Base(localFile))
+	return output.finish(fz.mbLogExportWriter(ctx, params, output))
+}
+
+// MbLogExportWriter : めるあど便の履歴をWriterに書き込む
+func (fz *FzAPI) MbLogExportWriter(ctx context.Context, params map[string]string, w io.Writer) error {
+	return fz.withRelogin(ctx, true, func() error {
+		return fz.mbLogExportWriter(ctx, params, w)
+	})
+}
+
+// mbLogExportWriter : MbLogExportWriterの処理
+func (fz *FzAPI) mbLogExportWriter(ctx context.Context, params map[string]string, w io.Writer) error {
+	ctx = withIdempotent(withFzOp(ctx, "admin/history", ""))
 	v := url.Values{}
 	v.Set("respmode", "csv")
 	for key, val := range params {
 		v.Set(key, val)
 	}
-	v.Set("valid_key", fz.LastResp.ValidKey)
-	req, _ := http.NewRequest("POST", fz.URL+"/mb/cgi-bin/index.cgi/admin/history/", strings.NewReader(v.Encode()))
+	v.Set("valid_key", fz.validKey())
+	req, err := fz.newFzRequest(ctx, "POST", fz.baseURL()+"/mb/cgi-bin/index.cgi/admin/history/", strings.NewReader(v.Encode()))
+	if err != nil {
+		return fmt.Errorf("MbLogExport - NewRequest Error: %w", err)
+	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", FileZenRAUserAgent)
-	req.AddCookie(&fz.FzSession)
-	resp, err := fz.client.Do(req)
+	fz.addSession(req)
+	resp, err := fz.doRequest(req)
 	if err != nil {
-		return fmt.Errorf("MbLogExport - POST Error: %v", err)
+		return fmt.Errorf("MbLogExport - POST Error: %w", err)
 	}
 	defer resp.Body.Close()
-	_, err = io.Copy(output, resp.Body)
+	if err := checkStatus(resp); err != nil {
+		return fmt.Errorf("MbLogExport - Status Error: %w", err)
+	}
+	pr := fz.newProgress(ctx, "history", resp.ContentLength)
+	defer pr.finish()
+	_, err = io.Copy(pr.writer(w), resp.Body)
 	if err != nil {
-		return fmt.Errorf("MbLogExport - io.Copy Error: %v", err)
+		return fmt.Errorf("MbLogExport - io.Copy Error: %w", err)
 	}
 	return nil
 }
 
 // MbExportCSV : めるあど便関連のCSVエクスポート
 func (fz *FzAPI) MbExportCSV(path, localFile string) error {
-	output, err := os.Create(localFile)
+	return fz.MbExportCSVContext(context.Background(), path, localFile)
+}
+
+// MbExportCSVContext : コンテキストを指定してめるあど便関連のCSVをエクスポートする
+func (fz *FzAPI) MbExportCSVContext(ctx context.Context, path, localFile string) error {
+	return fz.withRelogin(ctx, true, func() error {
+		return fz.mbExportCSV(ctx, path, localFile)
+	})
+}
+
+// mbExportCSV : MbExportCSVContextの処理
+func (fz *FzAPI) mbExportCSV(ctx context.Context, path, localFile string) error {
+	output := &outputFile{path: localFile}
+	ctx = withProgressName(ctx, filepath.Base(localFile))
+	return output.finish(fz.mbExportCSVWriter(ctx, path, output))
+}
+
+// MbExportCSVWriter : めるあど便関連のCSVをWriterに書き込む
+func (fz *FzAPI) MbExportCSVWriter(ctx context.Context, path string, w io.Writer) error {
+	return fz.withRelogin(ctx, true, func() error {
+		return fz.mbExportCSVWriter(ctx, path, w)
+	})
+}
+
+// mbExportCSVWriter : MbExportCSVWriterの処理
+func (fz *FzAPI) mbExportCSVWriter(ctx context.Context, path string, w io.Writer) error {
+	ctx = withIdempotent(withFzOp(ctx, path, ""))
+	v := url.Values{}
+	req, err := fz.newFzRequest(ctx, "GET", fz.baseURL()+"/mb/cgi-bin/index.cgi"+path, strings.NewReader(v.Encode()))
 	if err != nil {
-		return fmt.Errorf("MbCsvExport - os.Create Error: %v", err)
+		return fmt.Errorf("MbCsvExport - NewRequest Error: %w", err)
 	}
-	defer output.Close()
-	v := url.Values{}
-	req, _ := http.NewRequest("GET", fz.URL+"/mb/cgi-bin/index.cgi"+path, strings.NewReader(v.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", FileZenRAUserAgent)
 	req.Header.Set("Accept-Language", "ja,en")
-	req.AddCookie(&fz.FzSession)
-	resp, err := fz.client.Do(req)
+	fz.addSession(req)
+	resp, err := fz.doRequest(req)
 	if err != nil {
-		return fmt.Errorf("MbCsvExport - POST Error: %v", err)
+		return fmt.Errorf("MbCsvExport - POST Error: %w", err)
 	}
 	defer resp.Body.Close()
-	_, err = io.Copy(output, resp.Body)
+	if err := checkStatus(resp); err != nil {
+		return fmt.Errorf("MbCsvExport - Status Error: %w", err)
+	}
+	pr := fz.newProgress(ctx, path, resp.ContentLength)
+	defer pr.finish()
+	_, err = io.Copy(pr.writer(w), resp.Body)
 	if err != nil {
-		return fmt.Errorf("MbCsvExport - io.Copy Error: %v", err)
+		return fmt.Errorf("MbCsvExport - io.Copy Error: %w", err)
 	}
 	return nil
 }
@@ -877,54 +1049,78 @@ var ErrRedirectAttempted = errors.New("redirect")
 
 // MbImportCSV : めるあど便関連のCSVインポート
 func (fz *FzAPI) MbImportCSV(path, uid, localFile, fileKey string, replace bool) error {
-	file, err := os.Open(localFile)
+	return fz.MbImportCSVContext(context.Background(), path, uid, localFile, fileKey, replace)
+}
+
+// MbImportCSVContext : コンテキストを指定してめるあど便関連のCSVをインポートする
+func (fz *FzAPI) MbImportCSVContext(ctx context.Context, path, uid, localFile, fileKey string, replace bool) error {
+	return fz.withRelogin(ctx, false, func() error {
+		return fz.mbImportCSV(ctx, path, uid, localFile, fileKey, replace)
+	})
+}
+
+// mbImportCSV : MbImportCSVContextの処理
+func (fz *FzAPI) mbImportCSV(ctx context.Context, path, uid, localFile, fileKey string, replace bool) error {
+	fstat, err := os.Stat(localFile)
 	if err != nil {
-		return fmt.Errorf("MbImportCSV - os.Open Error: %v", err)
+		return fmt.Errorf("MbImportCSV - os.Stat Error: %w", err)
 	}
-	defer file.Close()
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile(fileKey, filepath.Base(localFile))
+	file, err := os.Open(localFile)
 	if err != nil {
-		return fmt.Errorf("MbImportCSV - writer.CreateFormFile Error: %v", err)
+		return fmt.Errorf("MbImportCSV - os.Open Error: %w", err)
 	}
-	_, _ = io.Copy(part, file)
-	_ = writer.WriteField("action", "import")
+	defer file.Close()
+	return fz.mbImportCSVReader(ctx, path, uid, file, filepath.Base(localFile), fstat.Size(), fileKey, replace)
+}
+
+// MbImportCSVReader : Readerの内容をめるあど便関連のCSVとしてインポートする
+// nameはCSVのファイル名、sizeが不明な場合は-1を指定する
+func (fz *FzAPI) MbImportCSVReader(ctx context.Context, path, uid string, r io.Reader, name string, size int64, fileKey string, replace bool) error {
+	return fz.withRelogin(ctx, false, func() error {
+		return fz.mbImportCSVReader(ctx, path, uid, r, name, size, fileKey, replace)
+	})
+}
+
+// mbImportCSVReader : MbImportCSVReaderの処理
+func (fz *FzAPI) mbImportCSVReader(ctx context.Context, path, uid string, r io.Reader, name string, size int64, fileKey string, replace bool) error {
+	ctx = withFzOp(ctx, path, "")
+	pr := fz.newProgress(ctx, name, size)
+	defer pr.finish()
+	files := []formFile{{key: fileKey, name: name, r: pr.reader(r), size: size}}
+	fields := formFields{}
+	fields.add("action", "import")
 	if uid != "" {
-		_ = writer.WriteField("uid", uid)
+		fields.add("uid", uid)
 	}
 	if replace {
-		_ = writer.WriteField("replace", "yes")
+		fields.add("replace", "yes")
 	}
-	err = writer.Close()
+	req, err := fz.newMultipartRequest(ctx, fz.baseURL()+"/mb/cgi-bin/index.cgi"+path, files, fields)
 	if err != nil {
-		return fmt.Errorf("MbImportCSV - writer.Close Error: %v", err)
+		return fmt.Errorf("MbImportCSV - NewRequest Error: %w", err)
 	}
-	req, _ := http.NewRequest("POST", fz.URL+"/mb/cgi-bin/index.cgi"+path, body)
-	req.Header.Set("User-Agent", FileZenRAUserAgent)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
 	req.Header.Set("Accept-Language", "ja,en")
-	req.AddCookie(&fz.FzSession)
-	fz.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+	fz.addSession(req)
+	// リダイレクトしないクライアントをこのリクエストだけで使用する
+	client := *fz.httpClient()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 		return ErrRedirectAttempted
 	}
-	defer func() {
-		fz.client.CheckRedirect = nil
-	}()
-	resp, err := fz.client.Do(req)
-	if err != nil && resp != nil && resp.StatusCode != 302 {
-		return fmt.Errorf("MbImportCSV - Post Error: %v", err)
+	resp, err := fz.doRequestWithRetry(&client, req)
+	if resp == nil || (err != nil && resp.StatusCode != 302) {
+		return fmt.Errorf("MbImportCSV - Post Error: %w", err)
 	}
+	defer resp.Body.Close()
 	loc := resp.Header.Get("Location")
 	if strings.Contains(loc, "/import") {
-		return fmt.Errorf("MbImportCSV - redirect error: %s", loc)
+		return fmt.Errorf("MbImportCSV - redirect error: %s: %w", loc, ErrInvalidParam)
 	}
 	bp, err := ioutil.ReadAll(resp.Body)
 	if err == nil && strings.Contains(string(bp), "アドレス帳ファイルが正しくありません") {
-		return fmt.Errorf("MbImportCSV - Invalid format")
+		return fmt.Errorf("MbImportCSV - Invalid format: %w", ErrInvalidParam)
 	}
 	if err == nil && strings.Contains(string(bp), "ユーザーIDの指定が正しくありません。") {
-		return fmt.Errorf("MbImportCSV - Invalid uid")
+		return fmt.Errorf("MbImportCSV - Invalid uid: %w", ErrInvalidParam)
 	}
 	return nil
 }
@@ -1050,6 +1246,11 @@ func getDate(d string, bStart bool) time.Time {
 
 // AdminExport : 管理者による設定CSVファイルのエクスポート
 func (fz *FzAPI) AdminExport(mode, outfile, sd, ed string) error {
+	return fz.AdminExportContext(context.Background(), mode, outfile, sd, ed)
+}
+
+// AdminExportContext : コンテキストを指定して設定CSVファイルをエクスポートする
+func (fz *FzAPI) AdminExportContext(ctx context.Context, mode, outfile, sd, ed string) error {
 	params := map[string]string{}
 	bMB := false
 	switch mode {
@@ -1114,13 +1315,18 @@ func (fz *FzAPI) AdminExport(mode, outfile, sd, ed string) error {
 		return fmt.Errorf("Invalid admin export mode")
 	}
 	if !bMB {
-		return fz.FzExportCSV(params, outfile)
+		return fz.FzExportCSVContext(ctx, params, outfile)
 	}
-	return fz.MbLogExport(params, outfile)
+	return fz.MbLogExportContext(ctx, params, outfile)
 }
 
 // AdminImport : 管理者による設定CSVファイルのインポート
 func (fz *FzAPI) AdminImport(mode, infile string) error {
+	return fz.AdminImportContext(context.Background(), mode, infile)
+}
+
+// AdminImportContext : コンテキストを指定して設定CSVファイルをインポートする
+func (fz *FzAPI) AdminImportContext(ctx context.Context, mode, infile string) error {
 	params := map[string]string{}
 	fileKey := "filename"
 	switch mode {
@@ -1141,11 +1347,16 @@ func (fz *FzAPI) AdminImport(mode, infile string) error {
 	default:
 		return fmt.Errorf("Invalid import Type")
 	}
-	return fz.FzImportCSV(params, infile, fileKey)
+	return fz.FzImportCSVContext(ctx, params, infile, fileKey)
 }
 
 // MbAdminExport : めるあど便承認者エクスポート
 func (fz *FzAPI) MbAdminExport(mode, uid, outfile string) error {
+	return fz.MbAdminExportContext(context.Background(), mode, uid, outfile)
+}
+
+// MbAdminExportContext : コンテキストを指定してめるあど便承認者をエクスポートする
+func (fz *FzAPI) MbAdminExportContext(ctx context.Context, mode, uid, outfile string) error {
 	path := ""
 	switch mode {
 	case "authority":
@@ -1159,11 +1370,16 @@ func (fz *FzAPI) MbAdminExport(mode, uid, outfile string) error {
 	default:
 		return fmt.Errorf("Invalid export type")
 	}
-	return fz.MbExportCSV(path, outfile)
+	return fz.MbExportCSVContext(ctx, path, outfile)
 }
 
 // MbAdminImport : めるあど便のCSVファイルインポート
 func (fz *FzAPI) MbAdminImport(mode, uid, infile string) error {
+	return fz.MbAdminImportContext(context.Background(), mode, uid, infile)
+}
+
+// MbAdminImportContext : コンテキストを指定してめるあど便のCSVファイルをインポートする
+func (fz *FzAPI) MbAdminImportContext(ctx context.Context, mode, uid, infile string) error {
 	path := ""
 	fileKey := "file"
 	replace := false
@@ -1185,7 +1401,7 @@ func (fz *FzAPI) MbAdminImport(mode, uid, infile string) error {
 	default:
 		return fmt.Errorf("Invalid MbAdminImport mode")
 	}
-	return fz.MbImportCSV(path, uid, infile, fileKey, replace)
+	return fz.MbImportCSVContext(ctx, path, uid, infile, fileKey, replace)
 }
 
 // FzcConfig : FileZen Client設定ファイルの定義
@@ -1196,8 +1412,115 @@ type FzcConfig struct {
 	LocalFolder  string `json:"LocalFolder"`
 	FzDownFolder string `json:"FzDownFolder"`
 	FzUpFolder   string `json:"FzUpFolder"`
-	NotifyTo     string `json:"NotifyTo"`
-	NotifyMode   string `json:"NotifyMode"`
+	// アップロードを通知する相手(ALL|INDIVIDUAL|NONE)
+	NotifyTo string `json:"NotifyTo"`
+	// 通知するイベント(DOWNLOAD|ALTER|DELETE、ALLは全て)
+	NotifyMode string `json:"NotifyMode"`
+	// 分割アップロードの再試行回数
+	PlUploadRetries int `json:"PlUploadRetries"`
+	// 分割アップロードの分割のサイズ(MB)
+	PlUploadChunkMB int `json:"PlUploadChunkMB"`
+	// 分割アップロードにするファイルのサイズ(MB)
+	PlUploadThresholdMB int `json:"PlUploadThresholdMB"`
+	// 分割アップロードで同時に送信する分割の数
+	PlUploadParallel int `json:"PlUploadParallel"`
+	// 全ての転送の合計の速度の上限(例: 10M)
+	RateLimit string `json:"RateLimit"`
+	// １つの転送の速度の上限(例: 512K)
+	TransferRateLimit string `json:"TransferRateLimit"`
+	// 時間帯毎の速度の上限(例: 09:00-18:00=1M/256K)
+	RateSchedule []string `json:"RateSchedule"`
+	// 一時的な失敗の最大の送信回数
+	RetryMax int `json:"RetryMax"`
+	// 最初の再試行までの待ち時間(秒)
+	RetryWait int `json:"RetryWait"`
+	// 再試行するHTTPのステータス(省略時は502,503,504)
+	RetryStatus []int `json:"RetryStatus"`
+	// プロキシのURL(省略時は環境変数HTTP_PROXY、HTTPS_PROXY、NO_PROXY)
+	Proxy string `json:"Proxy"`
+	// プロキシのBasic認証のユーザー
+	ProxyUser string `json:"ProxyUser"`
+	// プロキシのBasic認証のパスワード(暗号化して保存する)
+	ProxyPassword string `json:"ProxyPassword"`
+	// TLSの最小のバージョン(1.2など)
+	TLSMinVersion string `json:"TLSMinVersion"`
+	// めるあど便の１つのファイルのサイズの上限(例: 200M)
+	MailMaxFileSize string `json:"MailMaxFileSize"`
+	// めるあど便の１通の添付ファイルの合計のサイズの上限(例: 1G)
+	MailMaxTotalSize string `json:"MailMaxTotalSize"`
+}
+
+// TransportOptions : 設定ファイルの通信の設定
+func (c *FzcConfig) TransportOptions() (TransportOptions, error) {
+	o := TransportOptions{}
+	var err error
+	if o.Proxy, err = ProxyURL(c.Proxy, c.ProxyUser, c.ProxyPassword); err != nil {
+		return o, err
+	}
+	if o.TLSMinVersion, err = ParseTLSVersion(c.TLSMinVersion); err != nil {
+		return o, err
+	}
+	return o, nil
+}
+
+// UploadOptions : 設定ファイルのNotifyTo、NotifyModeからアップロードの設定を作成する
+func (c *FzcConfig) UploadOptions(regName, comment string) (UploadOptions, error) {
+	o := NewUploadOptions(regName, comment, c.NotifyTo, c.NotifyMode)
+	return o, o.Validate()
+}
+
+// MailLimitOptions : 設定ファイルのめるあど便の添付ファイルのサイズの上限
+func (c *FzcConfig) MailLimitOptions() (MailLimit, error) {
+	l := MailLimit{}
+	var err error
+	if l.MaxFileSize, err = ParseSize(c.MailMaxFileSize); err != nil {
+		return l, err
+	}
+	if l.MaxTotalSize, err = ParseSize(c.MailMaxTotalSize); err != nil {
+		return l, err
+	}
+	return l, nil
+}
+
+// RetryOptions : 設定ファイルの再試行の設定
+func (c *FzcConfig) RetryOptions() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: c.RetryMax,
+		BaseDelay:   time.Duration(c.RetryWait) * time.Second,
+		MaxDelay:    time.Minute,
+		Jitter:      0.2,
+		RetryStatus: c.RetryStatus,
+	}
+}
+
+// RateLimitOptions : 設定ファイルの転送速度の制限
+func (c *FzcConfig) RateLimitOptions() (RateLimit, error) {
+	rl := RateLimit{}
+	var err error
+	if rl.BytesPerSec, err = ParseRate(c.RateLimit); err != nil {
+		return rl, err
+	}
+	if rl.PerTransfer, err = ParseRate(c.TransferRateLimit); err != nil {
+		return rl, err
+	}
+	for _, s := range c.RateSchedule {
+		rs, err := ParseRateSchedule(s)
+		if err != nil {
+			return rl, err
+		}
+		rl.Schedule = append(rl.Schedule, rs)
+	}
+	return rl, nil
+}
+
+// PlUploadOptions : 設定ファイルの分割アップロードの設定
+func (c *FzcConfig) PlUploadOptions() PlUploadOptions {
+	return PlUploadOptions{
+		Retries:   c.PlUploadRetries,
+		ChunkSize: int64(c.PlUploadChunkMB) * 1024 * 1024,
+		Threshold: int64(c.PlUploadThresholdMB) * 1024 * 1024,
+		Parallel:  c.PlUploadParallel,
+	}
 }
 
 // LoadFzcConfig : FilZen Clientの設定ファイルを読み込む
@@ -1216,6 +1539,12 @@ func LoadFzcConfig(path, key string) (*FzcConfig, error) {
 			config.FzPassword = pass
 		}
 	}
+	if config.ProxyUser != "" && config.ProxyPassword != "" {
+		pass, err := decrypt(config.ProxyUser+key, config.ProxyPassword)
+		if err == nil {
+			config.ProxyPassword = pass
+		}
+	}
 	return config, nil
 }
 
@@ -1228,6 +1557,13 @@ func SaveFzcConfig(config *FzcConfig, path, key string) error {
 		}
 		config.FzPassword = pass
 	}
+	if config.ProxyUser != "" && config.ProxyPassword != "" {
+		pass, err := encrypt(config.ProxyUser+key, config.ProxyPassword)
+		if err != nil {
+			return fmt.Errorf("SaveFzcConfig err=%v", err)
+		}
+		config.ProxyPassword = pass
+	}
 	b, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return fmt.Errorf("SaveFzcConfig err=%v", err)
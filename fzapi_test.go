@@ -1,10 +1,19 @@
 package fzapi
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -384,3 +393,280 @@ func TestFzcConfig(t *testing.T) {
 		t.Errorf("Password save error saved password=%s", cs.FzPassword)
 	}
 }
+
+// fakeFzResp : 試験用のFileZenの応答
+const fakeFzResp = `<?xml version="1.0" encoding="UTF-8"?>
+<FileZen>
+<Lastop><Res>%s</Res></Lastop>
+<ProjectList>
+<Project Name="パブリック">
+<Folder Name="パブリック" Access="read,write" Id="1" Limit="">
+<File Key="k1" Name="test.txt" Owner="admin" Size="12" TimeStamp="1577836800" DrmFlag="0" PdfFlag="0"/>
+</Folder>
+</Project>
+</ProjectList>
+<ValidKey>vk</ValidKey>
+</FileZen>`
+
+// fakeFzHandler : 試験用FileZenの処理（action/sub_action毎）
+type fakeFzHandler func(w http.ResponseWriter, r *http.Request, form url.Values)
+
+// newFakeFz : 試験用のFileZenサーバーを起動する
+func newFakeFz(t *testing.T, handlers map[string]fakeFzHandler) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		form := url.Values{}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			if err := r.ParseMultipartForm(1024 * 1024); err == nil {
+				form = r.MultipartForm.Value
+			}
+		} else {
+			b, _ := ioutil.ReadAll(r.Body)
+			form, _ = url.ParseQuery(string(b))
+		}
+		k := form.Get("action") + "/" + form.Get("sub_action")
+		if strings.HasPrefix(r.URL.Path, "/mb/") {
+			k = r.URL.Path
+		}
+		if h, ok := handlers[k]; ok {
+			h(w, r, form)
+			return
+		}
+		switch k {
+		case "Login/auth":
+			http.SetCookie(w, &http.Cookie{Name: "SessionID", Value: "sid"})
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		case "Logout/show", "Mainmenu_file/show":
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		default:
+			fmt.Fprintf(w, fakeFzResp, "NG")
+		}
+	}))
+	return ts
+}
+
+// TestFzDownloadContextCancel : ダウンロードの途中で取り消す試験
+func TestFzDownloadContextCancel(t *testing.T) {
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"Mainmenu_file/download": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			w.Header().Set("Content-Length", "1048576")
+			w.Write([]byte("partial"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		},
+	})
+	defer ts.Close()
+	fz := &FzAPI{}
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	dir, err := ioutil.TempDir("", "fzdownload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	local := filepath.Join(dir, "test.txt")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := fz.FzDownloadContext(ctx, "k1", local); err == nil {
+		t.Error("FzDownloadContext canceled download is no err")
+	}
+	if _, err := os.Stat(local); err == nil {
+		t.Error("FzDownloadContext partial file remains")
+	}
+}
+
+// TestFzPlUploadContextCancel : 取り消したContextで分割アップロードする試験
+func TestFzPlUploadContextCancel(t *testing.T) {
+	posted := 0
+	ok := func(w http.ResponseWriter, r *http.Request, form url.Values) {
+		posted++
+		fmt.Fprintf(w, fakeFzResp, "OK")
+	}
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"Mainmenu_upload/plupload":  ok,
+		"Mainmenu_upload/do_upload": ok,
+	})
+	defer ts.Close()
+	fz := &FzAPI{}
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	ctx := context.Background()
+	if err := fz.FzPlUploadContext(ctx, filepath.Join("testdata", "test.txt"), "1", "test.txt", "test", "", ""); err != nil || posted == 0 {
+		t.Fatalf("FzPlUploadContext posted=%d err=%v", posted, err)
+	}
+	posted = 0
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := fz.FzPlUploadContext(ctx, filepath.Join("testdata", "test.txt"), "1", "test.txt", "test", "", ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("FzPlUploadContext canceled upload err=%v", err)
+	}
+	if posted != 0 {
+		t.Errorf("FzPlUploadContext canceled upload posted=%d", posted)
+	}
+}
+
+// TestFzAPIConcurrent : 複数のgoroutineから同時に使用する試験（go test -raceで実行する）
+func TestFzAPIConcurrent(t *testing.T) {
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"Mainmenu_upload/do_upload": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			if r.MultipartForm != nil {
+				fh := r.MultipartForm.File["filename"]
+				if len(fh) != 1 {
+					fmt.Fprintf(w, fakeFzResp, "NG")
+					return
+				}
+				f, _ := fh[0].Open()
+				b, _ := ioutil.ReadAll(f)
+				f.Close()
+				if string(b) != form.Get("reg_filename") {
+					fmt.Fprintf(w, fakeFzResp, "NG")
+					return
+				}
+			}
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		},
+		"Mainmenu_file/download": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			w.Header().Set("Content-Length", strconv.Itoa(len(form.Get("key"))))
+			w.Write([]byte(form.Get("key")))
+		},
+		"/mb/cgi-bin/index.cgi/job/addrbook/": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			w.Header().Set("Location", "/mb/cgi-bin/index.cgi/job/addrbook/")
+			w.WriteHeader(http.StatusFound)
+		},
+	})
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "fzconcurrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fz := &FzAPI{}
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	fz2 := &FzAPI{}
+	if err := fz2.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			api := fz
+			if i%2 == 0 {
+				api = fz2
+			}
+			name := fmt.Sprintf("upload%d", i)
+			local := filepath.Join(dir, name)
+			if err := ioutil.WriteFile(local, []byte(strings.Repeat(name, 1000)), 0600); err != nil {
+				errs <- err
+				return
+			}
+			if err := api.FzUpload(local, "1", strings.Repeat(name, 1000), "", "", ""); err != nil {
+				errs <- fmt.Errorf("FzUpload %d err=%v", i, err)
+			}
+			if err := api.FzPlUpload(local, "1", name, "", "", ""); err != nil {
+				errs <- fmt.Errorf("FzPlUpload %d err=%v", i, err)
+			}
+			down := filepath.Join(dir, "down"+name)
+			if err := api.FzDownload(name, down); err != nil {
+				errs <- fmt.Errorf("FzDownload %d err=%v", i, err)
+			} else if b, _ := ioutil.ReadFile(down); string(b) != name {
+				errs <- fmt.Errorf("FzDownload %d data=%s", i, b)
+			}
+			if err := api.FzReload(); err != nil {
+				errs <- fmt.Errorf("FzReload %d err=%v", i, err)
+			}
+			if err := api.MbImportCSV("/job/addrbook/", "", local, "addrbook_file", false); err != nil {
+				errs <- fmt.Errorf("MbImportCSV %d err=%v", i, err)
+			}
+			if api.FzFindFile("パブリック", "パブリック", "test.txt") == "" {
+				errs <- fmt.Errorf("FzFindFile %d not found", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// TestFzAPIReaderWriter : Reader/Writerを使うアップロード、ダウンロードの試験
+func TestFzAPIReaderWriter(t *testing.T) {
+	uploaded := map[string]string{}
+	var mu sync.Mutex
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"Mainmenu_upload/do_upload": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			fh := r.MultipartForm.File["filename"]
+			f, _ := fh[0].Open()
+			b, _ := ioutil.ReadAll(f)
+			f.Close()
+			mu.Lock()
+			uploaded[fh[0].Filename] = string(b)
+			mu.Unlock()
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		},
+		"Mainmenu_file/download": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			if form.Get("key") != "k1" {
+				w.Header().Set("Content-Length", "10")
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte("Not Found!"))
+				return
+			}
+			w.Header().Set("Content-Length", "12")
+			w.Write([]byte("downloadtest"))
+		},
+		"User_export/do_export": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			w.Write([]byte("uid,name\n"))
+		},
+	})
+	defer ts.Close()
+	fz := &FzAPI{}
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	ctx := context.Background()
+	if err := fz.FzUploadReader(ctx, strings.NewReader("report data"), "report.csv", -1, "1", "report.csv", "", "", ""); err != nil {
+		t.Errorf("FzUploadReader err=%v", err)
+	}
+	if uploaded["report.csv"] != "report data" {
+		t.Errorf("FzUploadReader data=%s", uploaded["report.csv"])
+	}
+	if err := fz.FzPlUploadReader(ctx, strings.NewReader("report data"), "report.csv", -1, "1", "report.csv", "", "", ""); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("FzPlUploadReader unknown size err=%v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := fz.FzDownloadWriter(ctx, "k1", buf); err != nil || buf.String() != "downloadtest" {
+		t.Errorf("FzDownloadWriter data=%s err=%v", buf.String(), err)
+	}
+	buf.Reset()
+	if err := fz.FzExportCSVWriter(ctx, map[string]string{"action": "User_export", "sub_action": "do_export"}, buf); err != nil || buf.String() != "uid,name\n" {
+		t.Errorf("FzExportCSVWriter data=%s err=%v", buf.String(), err)
+	}
+	// エラーの場合は、既存のファイルを変更しない
+	dir, err := ioutil.TempDir("", "fzreaderwriter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	local := filepath.Join(dir, "test.txt")
+	if err := ioutil.WriteFile(local, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fz.FzDownload("bad", local); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FzDownload bad key err=%v", err)
+	}
+	if b, _ := ioutil.ReadFile(local); string(b) != "old" {
+		t.Errorf("FzDownload bad key changed file data=%s", b)
+	}
+	if err := fz.FzDownload("k1", local); err != nil {
+		t.Errorf("FzDownload err=%v", err)
+	}
+	if b, _ := ioutil.ReadFile(local); string(b) != "downloadtest" {
+		t.Errorf("FzDownload data=%s", b)
+	}
+}
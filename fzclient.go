@@ -0,0 +1,225 @@
+package fzapi
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Option : NewClientの設定
+type Option func(*clientConfig) error
+
+// clientConfig : NewClientの設定を集める
+type clientConfig struct {
+	fz        *FzAPI
+	tls       bool // TLSの設定を指定した
+	transport bool // TransportOptionsを指定した
+}
+
+// NewClient : FileZenのURLと設定からFzAPIを作成する
+// URLと設定の組み合わせを確認して、HTTPクライアントを作成する。
+// 作成後に通信の設定のフィールドを変更しても反映されないため、
+// 設定は全てOptionで指定すること。
+// ログインは、fz.FzLogin("", uid, password)のようにURLを空にして行う
+func NewClient(baseURL string, opts ...Option) (*FzAPI, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("NewClient - Invalid URL %s: %w", baseURL, ErrInvalidParam)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("NewClient - Invalid URL %s: %w", baseURL, ErrInvalidParam)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("NewClient - URL has query %s: %w", baseURL, ErrInvalidParam)
+	}
+	cc := &clientConfig{fz: &FzAPI{URL: strings.TrimSuffix(baseURL, "/")}}
+	for _, opt := range opts {
+		if err := opt(cc); err != nil {
+			return nil, fmt.Errorf("NewClient - Option Error: %w", err)
+		}
+	}
+	fz := cc.fz
+	if fz.HTTPClient != nil && (fz.Transport != nil || cc.tls || cc.transport || fz.Timeout > 0) {
+		return nil, fmt.Errorf("NewClient - HTTPClient with TLS, transport or timeout options: %w", ErrInvalidParam)
+	}
+	if fz.Transport != nil && (cc.tls || cc.transport) {
+		return nil, fmt.Errorf("NewClient - Transport with TLS or transport options: %w", ErrInvalidParam)
+	}
+	if u.Scheme == "http" && cc.tls {
+		return nil, fmt.Errorf("NewClient - TLS options with http URL: %w", ErrInvalidParam)
+	}
+	fz.httpClient()
+	return fz, nil
+}
+
+// WithInsecureSkipVerify : サーバー証明書を検証しない
+func WithInsecureSkipVerify() Option {
+	return func(cc *clientConfig) error {
+		cc.fz.InsecureSkipVerify = true
+		cc.tls = true
+		return nil
+	}
+}
+
+// WithCACert : サーバー証明書の検証に使用するCA証明書(PEM)
+func WithCACert(pem []byte) Option {
+	return func(cc *clientConfig) error {
+		if len(pem) == 0 {
+			return fmt.Errorf("WithCACert - empty CA cert: %w", ErrInvalidParam)
+		}
+		cc.fz.CaCert = pem
+		cc.tls = true
+		return nil
+	}
+}
+
+// WithCAFile : サーバー証明書の検証に使用するCA証明書(PEM)のファイル
+func WithCAFile(path string) Option {
+	return func(cc *clientConfig) error {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("WithCAFile - ReadFile Error: %w", err)
+		}
+		return WithCACert(b)(cc)
+	}
+}
+
+// WithClientCert : クライアント証明書
+func WithClientCert(cert tls.Certificate) Option {
+	return func(cc *clientConfig) error {
+		if len(cert.Certificate) == 0 {
+			return fmt.Errorf("WithClientCert - empty client cert: %w", ErrInvalidClientCert)
+		}
+		cc.fz.ClientCert = cert
+		cc.fz.UseClientCert = true
+		cc.tls = true
+		return nil
+	}
+}
+
+// WithClientCertFile : クライアント証明書のファイル（証明書と秘密鍵のPEM）
+func WithClientCertFile(path, keypass string) Option {
+	return func(cc *clientConfig) error {
+		if err := cc.fz.LoadClientCert(path, keypass); err != nil {
+			return fmt.Errorf("WithClientCertFile - LoadClientCert Error: %w", err)
+		}
+		cc.tls = true
+		return nil
+	}
+}
+
+// WithTLSMinVersion : TLSの最小のバージョン(tls.VersionTLS12など)
+func WithTLSMinVersion(v uint16) Option {
+	return func(cc *clientConfig) error {
+		if v < tls.VersionTLS10 || v > tls.VersionTLS13 {
+			return fmt.Errorf("WithTLSMinVersion - Invalid TLS version %x: %w", v, ErrInvalidParam)
+		}
+		cc.fz.TransportOptions.TLSMinVersion = v
+		cc.tls = true
+		return nil
+	}
+}
+
+// WithTimeout : リクエスト全体のタイムアウト（秒単位、0は無制限）
+func WithTimeout(d time.Duration) Option {
+	return func(cc *clientConfig) error {
+		if d < 0 || d%time.Second != 0 {
+			return fmt.Errorf("WithTimeout - Invalid timeout %s: %w", d, ErrInvalidParam)
+		}
+		cc.fz.Timeout = int(d / time.Second)
+		return nil
+	}
+}
+
+// WithDialTimeout : 接続のタイムアウト
+func WithDialTimeout(d time.Duration) Option {
+	return func(cc *clientConfig) error {
+		if d <= 0 {
+			return fmt.Errorf("WithDialTimeout - Invalid timeout %s: %w", d, ErrInvalidParam)
+		}
+		cc.fz.TransportOptions.DialTimeout = d
+		cc.transport = true
+		return nil
+	}
+}
+
+// WithTransportOptions : HTTPの通信の設定
+// TLSMinVersionとDialTimeoutは、それぞれのOptionより前に指定すること
+func WithTransportOptions(o TransportOptions) Option {
+	return func(cc *clientConfig) error {
+		cc.fz.TransportOptions = o
+		cc.transport = true
+		return nil
+	}
+}
+
+// WithProxy : プロキシのURL(http://host:port)と認証情報
+func WithProxy(proxy, user, password string) Option {
+	return func(cc *clientConfig) error {
+		u, err := ProxyURL(proxy, user, password)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			return fmt.Errorf("WithProxy - empty proxy: %w", ErrInvalidParam)
+		}
+		cc.fz.TransportOptions.Proxy = u
+		cc.transport = true
+		return nil
+	}
+}
+
+// WithUserAgent : User-Agent（既定値はFileZenRAUserAgent）
+func WithUserAgent(ua string) Option {
+	return func(cc *clientConfig) error {
+		if ua == "" {
+			return fmt.Errorf("WithUserAgent - empty user agent: %w", ErrInvalidParam)
+		}
+		cc.fz.UserAgent = ua
+		return nil
+	}
+}
+
+// WithLogger : リクエストなどのログの出力先
+func WithLogger(l Logger) Option {
+	return func(cc *clientConfig) error {
+		cc.fz.Logger = l
+		return nil
+	}
+}
+
+// WithTransport : 使用するRoundTripper
+// TLSや通信の設定のOptionとは同時に指定できない
+func WithTransport(rt http.RoundTripper) Option {
+	return func(cc *clientConfig) error {
+		if rt == nil {
+			return fmt.Errorf("WithTransport - nil transport: %w", ErrInvalidParam)
+		}
+		cc.fz.Transport = rt
+		return nil
+	}
+}
+
+// WithHTTPClient : 使用するHTTPクライアント
+// TLS、通信の設定、タイムアウト、WithTransportとは同時に指定できない
+func WithHTTPClient(c *http.Client) Option {
+	return func(cc *clientConfig) error {
+		if c == nil {
+			return fmt.Errorf("WithHTTPClient - nil client: %w", ErrInvalidParam)
+		}
+		cc.fz.HTTPClient = c
+		return nil
+	}
+}
+
+// WithRetry : 一時的な失敗の再試行の設定
+func WithRetry(p RetryPolicy) Option {
+	return func(cc *clientConfig) error {
+		cc.fz.Retry = p
+		return nil
+	}
+}
@@ -0,0 +1,80 @@
+package fzapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestNewClient : NewClientの試験
+func TestNewClient(t *testing.T) {
+	var ua string
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"Login/auth": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			ua = r.Header.Get("User-Agent")
+			http.SetCookie(w, &http.Cookie{Name: "SessionID", Value: "sid"})
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		},
+	})
+	defer ts.Close()
+	ct := &countTransport{}
+	fz, err := NewClient(ts.URL+"/", WithTransport(ct), WithTimeout(10*time.Second), WithUserAgent("fztest"))
+	if err != nil {
+		t.Fatalf("NewClient err=%v", err)
+	}
+	if fz.URL != ts.URL {
+		t.Errorf("NewClient URL=%s", fz.URL)
+	}
+	if err := fz.FzLogin("", "test", "test"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	if ua != "fztest" || ct.n != 1 {
+		t.Errorf("NewClient ua=%s n=%d", ua, ct.n)
+	}
+	if fz.httpClient().Timeout != 10*time.Second {
+		t.Errorf("NewClient timeout=%s", fz.httpClient().Timeout)
+	}
+	// 既定のUser-Agent
+	fz, err = NewClient(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClient err=%v", err)
+	}
+	if err := fz.FzLogin("", "test", "test"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	if ua != FileZenRAUserAgent {
+		t.Errorf("NewClient ua=%s", ua)
+	}
+	// URLと設定の誤り
+	bad := []struct {
+		url  string
+		opts []Option
+	}{
+		{"", nil},
+		{"filezen.example", nil},
+		{"ftp://filezen.example", nil},
+		{"https://", nil},
+		{"https://filezen.example/?a=b", nil},
+		{"http://filezen.example", []Option{WithInsecureSkipVerify()}},
+		{"https://filezen.example", []Option{WithHTTPClient(&http.Client{}), WithTimeout(time.Second)}},
+		{"https://filezen.example", []Option{WithHTTPClient(&http.Client{}), WithTransport(ct)}},
+		{"https://filezen.example", []Option{WithTransport(ct), WithInsecureSkipVerify()}},
+		{"https://filezen.example", []Option{WithTransport(ct), WithProxy("proxy:8080", "", "")}},
+		{"https://filezen.example", []Option{WithTimeout(-time.Second)}},
+		{"https://filezen.example", []Option{WithCAFile("testdata/notfound.pem")}},
+		{"https://filezen.example", []Option{WithUserAgent("")}},
+		{"https://filezen.example", []Option{WithTLSMinVersion(0x0200)}},
+	}
+	for i, b := range bad {
+		if _, err := NewClient(b.url, b.opts...); err == nil {
+			t.Errorf("NewClient %d %s no error", i, b.url)
+		}
+	}
+	_, err = NewClient("https://filezen.example", WithHTTPClient(&http.Client{}), WithInsecureSkipVerify())
+	if !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("NewClient err=%v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package fzapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// FileZenのエラーの分類
+// errors.Is()で判定できる
+var (
+	// ErrSessionExpired : セッションの期限切れ、ValidKeyが無効
+	ErrSessionExpired = errors.New("session expired")
+	// ErrAuth : ログインの認証エラー
+	ErrAuth = errors.New("authentication failed")
+	// ErrPermission : アクセス権限がない
+	ErrPermission = errors.New("permission denied")
+	// ErrQuotaExceeded : 容量やサイズの制限を超えた
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrNotFound : ファイルやフォルダなどが見つからない
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidParam : パラメータが不正
+	ErrInvalidParam = errors.New("invalid parameter")
+	// ErrServer : FileZenのサーバーエラー(HTTP 5xxなど)
+	ErrServer = errors.New("server error")
+	// ErrNetwork : 通信エラー
+	ErrNetwork = errors.New("network error")
+)
+
+// FzError : FileZenがエラーを応答した場合のエラー
+type FzError struct {
+	Action     string // 送信したaction(めるあど便の場合はパス)
+	SubAction  string // 送信したsub_action
+	Res        string // 応答のLastop>Res
+	StatusCode int    // HTTPのステータスコード
+	Body       []byte // 応答の本文
+	Err        error  // エラーの分類(ErrSessionExpiredなど)
+}
+
+// Error : エラーの文字列
+func (e *FzError) Error() string {
+	s := fmt.Sprintf("FileZen error action=%s", e.Action)
+	if e.SubAction != "" {
+		s += " sub_action=" + e.SubAction
+	}
+	if e.Res != "" {
+		s += " res=" + e.Res
+	}
+	if e.StatusCode != 0 && e.StatusCode != http.StatusOK {
+		s += fmt.Sprintf(" status=%d", e.StatusCode)
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// Unwrap : errors.Is/Asのためにエラーの分類を返す
+func (e *FzError) Unwrap() error {
+	return e.Err
+}
+
+// classifyResp : ステータスコードからエラーの分類を決める
+// Resの値はFileZenで確認できていないため分類に使用しない（OK以外は分類なしのFzErrorになる）
+// XMLの応答の代わりにリダイレクトされた場合（ログイン画面など）は、ログイン以外ではセッションの期限切れとする
+func classifyResp(action string, status int, redirected bool) error {
+	if redirected && action != "Login" {
+		return ErrSessionExpired
+	}
+	switch {
+	case status == http.StatusUnauthorized:
+		return ErrAuth
+	case status == http.StatusForbidden:
+		return ErrPermission
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusRequestEntityTooLarge:
+		return ErrQuotaExceeded
+	case status >= 500:
+		return ErrServer
+	}
+	if action == "Login" {
+		return ErrAuth
+	}
+	return nil
+}
+
+// newFzError : 応答からFzErrorを作成する
+func newFzError(r *http.Response, res string, body []byte) *FzError {
+	e := &FzError{Res: res, Body: body}
+	if r != nil {
+		e.StatusCode = r.StatusCode
+		if r.Request != nil {
+			op := getFzOp(r.Request.Context())
+			e.Action, e.SubAction = op.action, op.subAction
+		}
+	}
+	e.Err = classifyResp(e.Action, e.StatusCode, isRedirected(r))
+	return e
+}
+
+// isRedirected : 応答がリダイレクトの後の応答か判定する
+func isRedirected(r *http.Response) bool {
+	return r != nil && r.Request != nil && r.Request.Response != nil
+}
+
+// netError : 通信エラー
+// 元のエラー(*url.Errorやcontext.Canceledなど)もerrors.Is/Asで判定できる
+type netError struct {
+	err error
+}
+
+func (e *netError) Error() string {
+	return e.err.Error()
+}
+
+func (e *netError) Unwrap() error {
+	return e.err
+}
+
+func (e *netError) Is(target error) bool {
+	return target == ErrNetwork
+}
+
+// fzOp : リクエストのactionとsub_action
+type fzOp struct {
+	action    string
+	subAction string
+}
+
+type fzOpKey struct{}
+
+// withFzOp : エラー情報のためにactionとsub_actionをコンテキストに保存する
+func withFzOp(ctx context.Context, action, subAction string) context.Context {
+	return context.WithValue(ctx, fzOpKey{}, fzOp{action: action, subAction: subAction})
+}
+
+// getFzOp : コンテキストからactionとsub_actionを取り出す
+func getFzOp(ctx context.Context) fzOp {
+	op, _ := ctx.Value(fzOpKey{}).(fzOp)
+	return op
+}
@@ -0,0 +1,78 @@
+package fzapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// TestFzError : エラーの分類の試験
+func TestFzError(t *testing.T) {
+	res := "NG"
+	status := http.StatusOK
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"Mainmenu_file/show": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			if status != http.StatusOK {
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+			fmt.Fprintf(w, fakeFzResp, res)
+		},
+		"Mainmenu_file/delete_file": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		},
+	})
+	defer ts.Close()
+	fz := &FzAPI{}
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	var tests = []struct {
+		status int
+		err    error
+	}{
+		{status: http.StatusUnauthorized, err: ErrAuth},
+		{status: http.StatusForbidden, err: ErrPermission},
+		{status: http.StatusNotFound, err: ErrNotFound},
+		{status: http.StatusRequestEntityTooLarge, err: ErrQuotaExceeded},
+		{status: http.StatusInternalServerError, err: ErrServer},
+	}
+	for _, e := range tests {
+		status = e.status
+		err := fz.FzReload()
+		if !errors.Is(err, e.err) {
+			t.Errorf("FzReload status=%d err=%v is not %v", e.status, err, e.err)
+		}
+		var fzErr *FzError
+		if !errors.As(err, &fzErr) {
+			t.Fatalf("FzReload status=%d err=%v is not FzError", e.status, err)
+		}
+		if fzErr.Action != "Mainmenu_file" || fzErr.SubAction != "show" || fzErr.StatusCode != e.status {
+			t.Errorf("FzReload FzError=%+v", fzErr)
+		}
+	}
+	// OK以外のResは分類しない
+	status = http.StatusOK
+	for _, r := range []string{"NG", "SESSION_TIMEOUT", "PERMISSION_DENIED"} {
+		res = r
+		err := fz.FzReload()
+		var fzErr *FzError
+		if !errors.As(err, &fzErr) || fzErr.Err != nil || fzErr.Res != r {
+			t.Errorf("FzReload res=%s err=%v is not generic FzError", r, err)
+		}
+	}
+	err := fz.FzDeleteFile("k1")
+	var fzErr *FzError
+	if !errors.Is(err, ErrServer) || !errors.As(err, &fzErr) || fzErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("FzDeleteFile err=%v is not server error", err)
+	}
+	// 通信エラー
+	ts.Close()
+	err = fz.FzReload()
+	var urlErr *url.Error
+	if !errors.Is(err, ErrNetwork) || !errors.As(err, &urlErr) {
+		t.Errorf("FzReload err=%v is not network error", err)
+	}
+}
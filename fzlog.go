@@ -0,0 +1,197 @@
+package fzapi
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLevel : ログのレベル
+type LogLevel int
+
+// ログのレベル
+const (
+	LogDebug LogLevel = iota // 成功したリクエストなど
+	LogInfo                  // 再ログインなど
+	LogWarn                  // 失敗したリクエスト、再試行など
+	LogError
+)
+
+// String : レベルの文字列
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogWarn:
+		return "warn"
+	}
+	return "error"
+}
+
+// LogFields : ログの項目
+// リクエストのログは、action、sub_action、method、path、status、latency(time.Duration)、
+// req_bytes、resp_bytes、res、error、form（パスワードなどは伏せ字）の項目を含む
+type LogFields map[string]interface{}
+
+// Logger : FzAPIのログの出力先
+// 複数のgoroutineから呼ばれる
+type Logger interface {
+	Log(level LogLevel, msg string, fields LogFields)
+}
+
+// LoggerFunc : 関数をLoggerとして使用する
+type LoggerFunc func(level LogLevel, msg string, fields LogFields)
+
+// Log : 関数を呼び出す
+func (f LoggerFunc) Log(level LogLevel, msg string, fields LogFields) {
+	f(level, msg, fields)
+}
+
+// redactKeys : ログで伏せ字にするフォームの項目
+var redactKeys = map[string]bool{
+	"password":        true,
+	"password_retype": true,
+	"passwd":          true,
+	"valid_key":       true,
+	"SessionID":       true,
+}
+
+// redactForm : フォームのパスワードなどを伏せ字にする
+func redactForm(v url.Values) string {
+	r := url.Values{}
+	for k, vals := range v {
+		for _, val := range vals {
+			if redactKeys[k] && val != "" {
+				val = "***"
+			}
+			r.Add(k, val)
+		}
+	}
+	return r.Encode()
+}
+
+// log : Loggerが設定されていればログを出力する
+func (fz *FzAPI) log(level LogLevel, msg string, fields LogFields) {
+	if fz.Logger != nil {
+		fz.Logger.Log(level, msg, fields)
+	}
+}
+
+// requestLog : １つのリクエストのログ
+// 応答の本文を閉じた時に出力する
+type requestLog struct {
+	mu     sync.Mutex
+	fz     *FzAPI
+	fields LogFields
+	start  time.Time
+	res    string
+}
+
+type requestLogKey struct{}
+
+// setLogRes : 応答のResをリクエストのログに記録する
+func setLogRes(r *http.Response, res string) {
+	if r == nil || r.Request == nil {
+		return
+	}
+	if rl, ok := r.Request.Context().Value(requestLogKey{}).(*requestLog); ok {
+		rl.mu.Lock()
+		rl.res = res
+		rl.mu.Unlock()
+	}
+}
+
+// output : リクエストのログを出力する
+func (rl *requestLog) output(n int64, err error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.fields["latency"] = time.Since(rl.start)
+	level := LogDebug
+	if n >= 0 {
+		rl.fields["resp_bytes"] = n
+	}
+	if rl.res != "" {
+		rl.fields["res"] = rl.res
+		if rl.res != "OK" {
+			level = LogWarn
+		}
+	}
+	if status, _ := rl.fields["status"].(int); status >= 400 {
+		level = LogWarn
+	}
+	if err != nil {
+		rl.fields["error"] = err.Error()
+		level = LogWarn
+	}
+	rl.fz.log(level, "request", rl.fields)
+}
+
+// doLogged : リクエストを送信してログを記録する
+func (fz *FzAPI) doLogged(c *http.Client, req *http.Request) (*http.Response, error) {
+	if fz.Logger == nil {
+		return c.Do(req)
+	}
+	op := getFzOp(req.Context())
+	rl := &requestLog{fz: fz, start: time.Now(), fields: LogFields{
+		"action":     op.action,
+		"sub_action": op.subAction,
+		"method":     req.Method,
+		"path":       req.URL.Path,
+		"req_bytes":  req.ContentLength,
+	}}
+	// FzSendPostReqはContent-Typeを設定しないで、フォームを送信する
+	if ct := req.Header.Get("Content-Type"); req.GetBody != nil && (ct == "" || strings.HasPrefix(ct, "application/x-www-form-urlencoded")) {
+		if body, err := req.GetBody(); err == nil {
+			b, _ := ioutil.ReadAll(body)
+			body.Close()
+			if v, err := url.ParseQuery(string(b)); err == nil {
+				rl.fields["form"] = redactForm(v)
+			}
+		}
+	}
+	req = req.WithContext(context.WithValue(req.Context(), requestLogKey{}, rl))
+	resp, err := c.Do(req)
+	if resp != nil {
+		rl.fields["status"] = resp.StatusCode
+	}
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			resp.Body = &logBody{ReadCloser: resp.Body, rl: rl, err: err}
+			return resp, err
+		}
+		rl.output(-1, err)
+		return resp, err
+	}
+	resp.Body = &logBody{ReadCloser: resp.Body, rl: rl}
+	return resp, nil
+}
+
+// logBody : 読み込んだバイト数を数えて、閉じた時にログを出力する本文
+type logBody struct {
+	io.ReadCloser
+	rl   *requestLog
+	n    int64
+	err  error
+	once sync.Once
+}
+
+func (b *logBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *logBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.rl.output(b.n, b.err)
+	})
+	return err
+}
@@ -0,0 +1,51 @@
+package fzapi
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// logEntry : 記録したログ
+type logEntry struct {
+	level  LogLevel
+	msg    string
+	fields LogFields
+}
+
+// TestFzLogger : リクエストのログの試験
+func TestFzLogger(t *testing.T) {
+	ts := newFakeFz(t, nil)
+	defer ts.Close()
+	var mu sync.Mutex
+	var logs []logEntry
+	fz := &FzAPI{Logger: LoggerFunc(func(level LogLevel, msg string, fields LogFields) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs = append(logs, logEntry{level: level, msg: msg, fields: fields})
+	})}
+	if err := fz.FzLogin(ts.URL, "admin", "secret"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	params := map[string]string{"action": "User_import", "sub_action": "do_import"}
+	if err := fz.FzImportCSVReader(context.Background(), params, strings.NewReader("a,b\n"), "user.csv", 4, "csvfile"); err == nil {
+		t.Error("FzImportCSVReader no err")
+	}
+	if len(logs) != 2 {
+		t.Fatalf("logs=%+v", logs)
+	}
+	l := logs[0]
+	form, _ := l.fields["form"].(string)
+	if l.level != LogDebug || l.msg != "request" || l.fields["action"] != "Login" || l.fields["sub_action"] != "auth" ||
+		l.fields["status"] != 200 || l.fields["res"] != "OK" || l.fields["path"] != "/cgi-bin/index.cgi" {
+		t.Errorf("Login log=%+v", l)
+	}
+	if strings.Contains(form, "secret") || !strings.Contains(form, "password=%2A%2A%2A") || !strings.Contains(form, "user_id=admin") {
+		t.Errorf("Login log form=%s", form)
+	}
+	l = logs[1]
+	if l.level != LogWarn || l.fields["action"] != "User_import" || l.fields["res"] != "NG" || l.fields["req_bytes"].(int64) <= 4 {
+		t.Errorf("Import log=%+v", l)
+	}
+}
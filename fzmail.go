@@ -0,0 +1,478 @@
+package fzapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxMailFiles : めるあど便で１通に添付できるファイルの数(file1からfile5)
+const MaxMailFiles = 5
+
+// DefaultMailMaxFileSize : めるあど便で添付できる１つのファイルのサイズの上限の既定値
+const DefaultMailMaxFileSize = 1024 * 1024 * 200
+
+// MailLimit : めるあど便の添付ファイルのサイズの上限
+// FileZenの設定に合わせて指定する（FileZenから上限を取得する機能は未実装）
+type MailLimit struct {
+	MaxFileSize  int64 // １つのファイルの上限（0の場合はDefaultMailMaxFileSize）
+	MaxTotalSize int64 // １通の添付ファイルの合計の上限（0の場合は制限しない）
+}
+
+// maxFileSize : １つのファイルの上限
+func (l MailLimit) maxFileSize() int64 {
+	if l.MaxFileSize <= 0 {
+		return DefaultMailMaxFileSize
+	}
+	return l.MaxFileSize
+}
+
+// MailSplit : 上限を超えるファイルの分割の方法
+// 分割したファイルは、元のファイル名に.001、.002...を付けて送信する（連結すると元に戻る）
+type MailSplit int
+
+// 上限を超えるファイルの分割の方法
+const (
+	MailSplitNone        MailSplit = iota // 分割しない（上限を超える場合はエラー）
+	MailSplitAttachments                  // 分割したファイルを１通の添付ファイルにする
+	MailSplitMails                        // 分割したファイルを複数のめるあど便で送信する
+)
+
+// ParseMailSplit : 分割の方法の文字列(none|attachments|mails)を変換する
+func ParseMailSplit(s string) (MailSplit, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return MailSplitNone, nil
+	case "attachments":
+		return MailSplitAttachments, nil
+	case "mails":
+		return MailSplitMails, nil
+	}
+	return MailSplitNone, fmt.Errorf("Invalid split %s: %w", s, ErrInvalidParam)
+}
+
+// めるあど便のメールの言語(lang)
+const (
+	MailLangBoth     = "ambi" // 日本語と英語
+	MailLangJapanese = "ja"
+	MailLangEnglish  = "en"
+)
+
+// MailAddress : めるあど便の宛先、送信元のメールアドレス
+type MailAddress struct {
+	Name  string
+	Email string
+}
+
+// ParseMailAddress : "名前<email>"または"email"の形式のメールアドレスを変換する
+func ParseMailAddress(s string) (MailAddress, error) {
+	a := getEMailAddr(s)
+	if a.Email == "" {
+		return MailAddress{}, fmt.Errorf("Invalid mail address %s: %w", s, ErrInvalidParam)
+	}
+	return MailAddress{Name: strings.TrimSpace(a.Name), Email: a.Email}, nil
+}
+
+// MailFile : めるあど便に添付するファイル
+// Pathにファイルまたはディレクトリ（ZIPファイルにして送信する）を指定するか、
+// ReaderとName、Sizeを指定する
+type MailFile struct {
+	Path   string
+	Reader io.Reader
+	Name   string // 送信するファイル名（省略時はPathのファイル名）
+	Size   int64  // Readerから読み込むサイズ
+}
+
+// MailJob : めるあど便の送信の内容
+type MailJob struct {
+	Subject        string        // 件名
+	From           string        // 送信元のメールアドレス
+	To             []MailAddress // 宛先
+	Files          []MailFile    // 添付ファイル（最大MaxMailFiles）
+	Start          time.Time     // 公開開始日時（分単位、ゼロの場合は当日の0時）
+	Days           int           // 公開期間（日数）
+	DownloadLimit  int           // ダウンロード回数の制限
+	Password       string        // ダウンロードのパスワード
+	NotifyDownload bool          // ダウンロードされた時に通知する
+	Lang           string        // メールの言語（省略時はMailLangBoth）
+	Comment        string        // 本文
+	Split          MailSplit     // 上限を超えるファイルの分割の方法
+	VolumeSize     int64         // 分割するサイズ（0または上限を超える場合は上限のサイズ）
+}
+
+// Validate : 送信の内容を確認する
+func (job *MailJob) Validate() error {
+	if _, err := ParseMailAddress(job.From); err != nil {
+		return fmt.Errorf("Invalid from: %w", err)
+	}
+	if len(job.To) < 1 {
+		return fmt.Errorf("No mailto: %w", ErrInvalidParam)
+	}
+	for _, to := range job.To {
+		if _, err := ParseMailAddress(to.Email); err != nil {
+			return fmt.Errorf("Invalid mailto: %w", err)
+		}
+	}
+	if len(job.Files) < 1 {
+		return fmt.Errorf("No file: %w", ErrInvalidParam)
+	}
+	if len(job.Files) > MaxMailFiles {
+		return fmt.Errorf("Too many files %d > %d: %w", len(job.Files), MaxMailFiles, ErrInvalidParam)
+	}
+	for i, f := range job.Files {
+		switch {
+		case f.Path != "" && f.Reader != nil:
+			return fmt.Errorf("File %d has both path and reader: %w", i+1, ErrInvalidParam)
+		case f.Path == "" && f.Reader == nil:
+			return fmt.Errorf("File %d has no path or reader: %w", i+1, ErrInvalidParam)
+		case f.Reader != nil && (f.Name == "" || f.Size < 0):
+			return fmt.Errorf("File %d reader needs name and size: %w", i+1, ErrInvalidParam)
+		}
+	}
+	if job.Days < 1 {
+		return fmt.Errorf("Invalid days %d: %w", job.Days, ErrInvalidParam)
+	}
+	if job.DownloadLimit < 0 {
+		return fmt.Errorf("Invalid limit %d: %w", job.DownloadLimit, ErrInvalidParam)
+	}
+	if job.Split < MailSplitNone || job.Split > MailSplitMails {
+		return fmt.Errorf("Invalid split %d: %w", int(job.Split), ErrInvalidParam)
+	}
+	if job.VolumeSize < 0 {
+		return fmt.Errorf("Invalid volume size %d: %w", job.VolumeSize, ErrInvalidParam)
+	}
+	switch job.Lang {
+	case "", MailLangBoth, MailLangJapanese, MailLangEnglish:
+	default:
+		return fmt.Errorf("Invalid lang %s: %w", job.Lang, ErrInvalidParam)
+	}
+	return nil
+}
+
+// mailTimeFormat : めるあど便の送信ファイルの日時の形式（時刻は省略できる）
+const mailTimeFormat = "2006/01/02 15:04"
+
+// ParseMailTime : めるあど便の日時の文字列(2006/01/02 15:04または2006/01/02)を変換する
+func ParseMailTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, f := range []string{mailTimeFormat, "2006/01/02"} {
+		if t, err := time.ParseInLocation(f, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid date %s: %w", s, ErrInvalidParam)
+}
+
+// termStart : 公開開始日時（ゼロの場合は当日の0時）
+func termStart(start time.Time) time.Time {
+	if start.IsZero() {
+		y, m, d := time.Now().Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}
+	return start
+}
+
+// addTermFields : 公開期間のフィールドを追加する
+func addTermFields(add func(k, v string), start time.Time, days int) {
+	st := termStart(start)
+	add("exp_term_start_year", fmt.Sprintf("%d", st.Year()))
+	add("exp_term_start_month", fmt.Sprintf("%d", st.Month()))
+	add("exp_term_start_day", fmt.Sprintf("%d", st.Day()))
+	add("exp_term_start_hour", fmt.Sprintf("%02d", st.Hour()))
+	add("exp_term_start_minute", fmt.Sprintf("%02d", st.Minute()))
+	add("exp_term_type", "by_dur")
+	add("exp_term_duration", fmt.Sprintf("%d", days))
+}
+
+// LoadMailJob : めるあど便の送信ファイルを読み込む
+func LoadMailJob(mbConf string) (*MailJob, error) {
+	return loadMbConf(mbConf)
+}
+
+// setMbOpt : めるあど便の送信ファイルの項目を設定する
+func (job *MailJob) setMbOpt(k, v string) error {
+	var err error
+	switch k {
+	case "subject":
+		job.Subject = v
+	case "mailto":
+		for _, to := range strings.Split(v, ",") {
+			if strings.TrimSpace(to) == "" {
+				continue
+			}
+			a, err := ParseMailAddress(to)
+			if err != nil {
+				return err
+			}
+			job.To = append(job.To, a)
+		}
+	case "from":
+		job.From = v
+	case "file":
+		job.Files = append(job.Files, MailFile{Path: v})
+	case "start":
+		if job.Start, err = ParseMailTime(v); err != nil {
+			return err
+		}
+	case "days":
+		if job.Days, err = strconv.Atoi(v); err != nil {
+			return fmt.Errorf("Invalid days %s: %w", v, ErrInvalidParam)
+		}
+	case "limit":
+		if job.DownloadLimit, err = strconv.Atoi(v); err != nil {
+			return fmt.Errorf("Invalid limit %s: %w", v, ErrInvalidParam)
+		}
+	case "password":
+		job.Password = v
+	case "notify":
+		job.NotifyDownload = v == "true"
+	case "lang":
+		job.Lang = v
+	case "comment":
+		job.Comment = v
+	case "split":
+		if job.Split, err = ParseMailSplit(v); err != nil {
+			return err
+		}
+	case "volume":
+		if job.VolumeSize, err = ParseSize(v); err != nil {
+			return err
+		}
+	}
+	// 以前と同じく不明な項目は無視する
+	return nil
+}
+
+// checkMbOpts : 以前と同じく省略できない項目(start、limit)を確認する
+func checkMbOpts(has func(k string) bool) error {
+	if !has("start") {
+		return fmt.Errorf("Invalid start date: %w", ErrInvalidParam)
+	}
+	if !has("limit") {
+		return fmt.Errorf("Invalid limit: %w", ErrInvalidParam)
+	}
+	return nil
+}
+
+// NewMailJob : 従来のFzSendMBの項目からめるあど便の送信の内容を作成する
+// mailtoはカンマ区切り、fileは１つ。以前と同じく不明な項目は無視し、startとlimitは省略できない
+func NewMailJob(mbconf map[string]string) (*MailJob, error) {
+	if err := checkMbOpts(func(k string) bool { _, ok := mbconf[k]; return ok }); err != nil {
+		return nil, err
+	}
+	job := &MailJob{}
+	for k, v := range mbconf {
+		if err := job.setMbOpt(k, v); err != nil {
+			return nil, err
+		}
+	}
+	return job, nil
+}
+
+// FzSendMailJob : めるあど便を送信する
+// ディレクトリはZIPファイルにして送信する。
+// MailLimitの上限を超えるファイルは、job.Splitに従って分割する。
+// 複数のめるあど便で送信する場合は、件名に「 (1/3)」のような番号を付ける。
+// 途中で失敗した場合は、送信済みの件名をエラーに含める
+func (fz *FzAPI) FzSendMailJob(ctx context.Context, job *MailJob) error {
+	if err := job.Validate(); err != nil {
+		return fmt.Errorf("FzSendMB - MailJob Error: %w", err)
+	}
+	files := make([]formFile, 0, len(job.Files))
+	for _, f := range job.Files {
+		if f.Reader != nil {
+			files = append(files, formFile{name: f.Name, r: f.Reader, size: f.Size})
+			continue
+		}
+		path := f.Path
+		fstat, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("FzSendMB - os.Stat Error: %w", err)
+		}
+		if fstat.IsDir() {
+			dir, err := ioutil.TempDir("", "fzmail")
+			if err != nil {
+				return fmt.Errorf("FzSendMB - TempDir Error: %w", err)
+			}
+			defer os.RemoveAll(dir)
+			if path, err = makeZip(path, filepath.Join(dir, filepath.Base(path)+".zip")); err != nil {
+				return fmt.Errorf("FzSendMB - makeZip Error: %w", err)
+			}
+			if fstat, err = os.Stat(path); err != nil {
+				return fmt.Errorf("FzSendMB - os.Stat Error: %w", err)
+			}
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("FzSendMB - os.Open Error: %w", err)
+		}
+		defer file.Close()
+		name := f.Name
+		if name == "" {
+			name = filepath.Base(path)
+		}
+		files = append(files, formFile{name: name, r: file, size: fstat.Size()})
+	}
+	files, err := fz.MailLimit.splitFiles(files, job.Split, job.VolumeSize)
+	if err != nil {
+		return fmt.Errorf("FzSendMB - Split Error: %w", err)
+	}
+	mails, err := fz.MailLimit.groupFiles(files, job.Split)
+	if err != nil {
+		return fmt.Errorf("FzSendMB - Split Error: %w", err)
+	}
+	sent := []string{}
+	for i, m := range mails {
+		subject := job.Subject
+		if len(mails) > 1 {
+			subject = fmt.Sprintf("%s (%d/%d)", job.Subject, i+1, len(mails))
+		}
+		err := fz.withRelogin(ctx, false, func() error {
+			return fz.fzSendMailJob(ctx, job, subject, m)
+		})
+		if err != nil && len(mails) > 1 {
+			// 送信済みのめるあど便は取り消せないため、どれが届いたかをエラーに含める
+			return fmt.Errorf("FzSendMB - mail %d/%d Error (sent: %q): %w", i+1, len(mails), sent, err)
+		}
+		if err != nil {
+			return err
+		}
+		sent = append(sent, subject)
+	}
+	return nil
+}
+
+// splitFiles : 上限を超えるファイルを分割する
+func (l MailLimit) splitFiles(files []formFile, split MailSplit, volume int64) ([]formFile, error) {
+	max := l.maxFileSize()
+	if volume <= 0 || volume > max {
+		volume = max
+	}
+	ret := []formFile{}
+	for _, f := range files {
+		if f.size <= max {
+			ret = append(ret, f)
+			continue
+		}
+		if split == MailSplitNone {
+			return nil, fmt.Errorf("File %s size %d over %d: %w", f.name, f.size, max, ErrInvalidParam)
+		}
+		ra, ok := f.r.(io.ReaderAt)
+		if !ok {
+			return nil, fmt.Errorf("File %s cannot split without io.ReaderAt: %w", f.name, ErrInvalidParam)
+		}
+		n := int((f.size + volume - 1) / volume)
+		for i := 0; i < n; i++ {
+			off := int64(i) * volume
+			size := volume
+			if f.size-off < size {
+				size = f.size - off
+			}
+			ret = append(ret, formFile{name: fmt.Sprintf("%s.%03d", f.name, i+1), r: io.NewSectionReader(ra, off, size), size: size})
+		}
+	}
+	return ret, nil
+}
+
+// groupFiles : 添付ファイルをめるあど便毎に分ける
+// MailSplitMails以外は１通にする
+func (l MailLimit) groupFiles(files []formFile, split MailSplit) ([][]formFile, error) {
+	mails := [][]formFile{}
+	if split == MailSplitMails {
+		var cur []formFile
+		var size int64
+		for _, f := range files {
+			if len(cur) > 0 && (len(cur) >= MaxMailFiles || (l.MaxTotalSize > 0 && size+f.size > l.MaxTotalSize)) {
+				mails = append(mails, cur)
+				cur, size = nil, 0
+			}
+			cur = append(cur, f)
+			size += f.size
+		}
+		mails = append(mails, cur)
+	} else {
+		mails = append(mails, files)
+	}
+	for _, m := range mails {
+		if len(m) > MaxMailFiles {
+			return nil, fmt.Errorf("Too many files %d > %d: %w", len(m), MaxMailFiles, ErrInvalidParam)
+		}
+		var total int64
+		for _, f := range m {
+			total += f.size
+		}
+		if l.MaxTotalSize > 0 && total > l.MaxTotalSize {
+			return nil, fmt.Errorf("Total size %d over %d: %w", total, l.MaxTotalSize, ErrInvalidParam)
+		}
+	}
+	return mails, nil
+}
+
+// fzSendMailJob : FzSendMailJobの処理
+func (fz *FzAPI) fzSendMailJob(ctx context.Context, job *MailJob, subject string, files []formFile) error {
+	ctx = withFzOp(ctx, "job/api_send", "")
+	names := []string{}
+	var total int64
+	for _, f := range files {
+		names = append(names, f.name)
+		total += f.size
+	}
+	pr := fz.newProgress(ctx, strings.Join(names, ","), total)
+	defer pr.finish()
+	th := fz.newThrottle(ctx)
+	sends := make([]formFile, len(files))
+	for i, f := range files {
+		sends[i] = formFile{key: fmt.Sprintf("file%d", i+1), name: f.name, r: pr.reader(th.reader(f.r)), size: f.size}
+	}
+	lang := job.Lang
+	if lang == "" {
+		lang = MailLangBoth
+	}
+	fields := formFields{}
+	fields.add("subject", subject)
+	fields.add("comment", job.Comment)
+	addTermFields(fields.add, job.Start, job.Days)
+	fields.add("download_times", fmt.Sprintf("%d", job.DownloadLimit))
+	fields.add("recipients-max-id", fmt.Sprintf("%d", len(job.To)))
+	for i, to := range job.To {
+		name := to.Name
+		if name == "" {
+			name = to.Email
+		}
+		fields.add(fmt.Sprintf("recipient-name-%d", i+1), name)
+		fields.add(fmt.Sprintf("recipient-email-%d", i+1), to.Email)
+	}
+	fields.add("from_addr", "user")
+	fields.add("from_addr_val", job.From)
+	fields.add("lang", lang)
+	fields.add("password", job.Password)
+	fields.add("password_retype", job.Password)
+	for i := len(files) + 1; i <= MaxMailFiles; i++ {
+		fields.add(fmt.Sprintf("file%d", i), "")
+	}
+	fields.add("respmode", "xml")
+	fields.add("valid_key", fz.validKey())
+	fields.add("key", "")
+	if job.NotifyDownload {
+		fields.add("notify_download", "1")
+	} else {
+		fields.add("notify_download", "0")
+	}
+	fields.add("new_alert", "1")
+	req, err := fz.newMultipartRequest(ctx, fz.baseURL()+"/mb/cgi-bin/index.cgi/job/api_send/", sends, fields)
+	if err != nil {
+		return fmt.Errorf("FzSendMB - NewRequest Error: %w", err)
+	}
+	fz.addSession(req)
+	resp, err := fz.doRequest(req)
+	if err != nil {
+		return fmt.Errorf("FzSendMB - POST Error: %w", err)
+	}
+	return fz.ParseXMLResp(resp)
+}
@@ -0,0 +1,267 @@
+package fzapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMailJob : めるあど便の複数のファイルの送信の試験
+func TestMailJob(t *testing.T) {
+	var form url.Values
+	sent := map[string]string{}
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"/mb/cgi-bin/index.cgi/job/api_send/": func(w http.ResponseWriter, r *http.Request, f url.Values) {
+			form = f
+			for k, fhs := range r.MultipartForm.File {
+				fr, _ := fhs[0].Open()
+				b, _ := ioutil.ReadAll(fr)
+				fr.Close()
+				sent[k] = fhs[0].Filename + ":" + string(b)
+			}
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		},
+	})
+	defer ts.Close()
+	fz := &FzAPI{}
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	dir, err := ioutil.TempDir("", "fzmailtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a := filepath.Join(dir, "a.txt")
+	ioutil.WriteFile(a, []byte("aaa"), 0600)
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0700)
+	ioutil.WriteFile(filepath.Join(sub, "b.txt"), []byte("bbb"), 0600)
+	mbconf := filepath.Join(dir, "mb.txt")
+	conf := "subject:Test\nmailto:Test <to@example.com>,to2@example.com\nfrom:from@example.com\n" +
+		"file:" + a + "\nfile:" + sub + "\nstart:2020/04/01\ndays:3\nlimit:2\nlang:ja\ncomment:Line1\nLine2:x\n"
+	ioutil.WriteFile(mbconf, []byte(conf), 0600)
+	job, err := LoadMailJob(mbconf)
+	if err != nil {
+		t.Fatalf("LoadMailJob err=%v", err)
+	}
+	if len(job.Files) != 2 || len(job.To) != 2 || job.To[0].Name != "Test" || job.Days != 3 || job.Comment != "Line1\nLine2:x\n" {
+		t.Errorf("LoadMailJob job=%+v", job)
+	}
+	job.Files = append(job.Files, MailFile{Reader: strings.NewReader("ccc"), Name: "c.txt", Size: 3})
+	if err := fz.FzSendMailJob(context.Background(), job); err != nil {
+		t.Fatalf("FzSendMailJob err=%v", err)
+	}
+	if sent["file1"] != "a.txt:aaa" || !strings.HasPrefix(sent["file2"], "sub.zip:PK") || sent["file3"] != "c.txt:ccc" {
+		t.Errorf("FzSendMailJob files=%v", sent)
+	}
+	if form.Get("file4") != "" || form.Get("lang") != "ja" || form.Get("exp_term_start_month") != "4" || form.Get("recipient-email-2") != "to2@example.com" || form.Get("download_times") != "2" {
+		t.Errorf("FzSendMailJob form=%v", form)
+	}
+	if err := fz.FzMail(mbconf); err != nil {
+		t.Errorf("FzMail err=%v", err)
+	}
+	// 誤り
+	bad := []*MailJob{
+		{From: "from@example.com", To: job.To, Days: 1},
+		{From: "from", To: job.To, Files: job.Files[:1], Days: 1},
+		{From: "from@example.com", Files: job.Files[:1], Days: 1},
+		{From: "from@example.com", To: job.To, Files: make([]MailFile, 6), Days: 1},
+		{From: "from@example.com", To: job.To, Files: []MailFile{{Reader: strings.NewReader("")}}, Days: 1},
+		{From: "from@example.com", To: job.To, Files: job.Files[:1]},
+		{From: "from@example.com", To: job.To, Files: job.Files[:1], Days: 1, Lang: "fr"},
+	}
+	for i, b := range bad {
+		if err := fz.FzSendMailJob(context.Background(), b); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("FzSendMailJob bad %d err=%v", i, err)
+		}
+	}
+	// 以前と同じく不明な項目は無視する
+	mb := map[string]string{"subjet": "typo", "mailto": "to@example.com", "from": "from@example.com", "file": a, "start": "2020/04/01", "days": "1", "limit": "1"}
+	if err := fz.FzSendMB(mb); err != nil {
+		t.Errorf("FzSendMB err=%v", err)
+	}
+	ioutil.WriteFile(mbconf, []byte("subjet:typo\nmailto:to@example.com\nfrom:from@example.com\nfile:"+a+"\nstart:2020/04/01\ndays:1\nlimit:1\n"), 0600)
+	if err := fz.FzMail(mbconf); err != nil {
+		t.Errorf("FzMail unknown key err=%v", err)
+	}
+	// 以前と同じくstartとlimitは省略できない
+	for _, k := range []string{"start", "limit"} {
+		m := map[string]string{}
+		for mk, mv := range mb {
+			m[mk] = mv
+		}
+		delete(m, k)
+		if err := fz.FzSendMB(m); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("FzSendMB no %s err=%v", k, err)
+		}
+		conf := ""
+		for mk, mv := range m {
+			conf += mk + ":" + mv + "\n"
+		}
+		ioutil.WriteFile(mbconf, []byte(conf), 0600)
+		if err := fz.FzMail(mbconf); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("FzMail no %s err=%v", k, err)
+		}
+	}
+	if err := fz.FzSendMB(map[string]string{"mailto": "to@example.com", "from": "from@example.com", "file": a, "start": "2020/04/01", "days": "x", "limit": "1"}); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("FzSendMB invalid days err=%v", err)
+	}
+}
+
+// TestMailSplit : めるあど便の上限を超えるファイルの分割の試験
+func TestMailSplit(t *testing.T) {
+	type mail struct {
+		subject string
+		files   []string
+	}
+	var mails []mail
+	data := map[string]string{}
+	fail := ""
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"/mb/cgi-bin/index.cgi/job/api_send/": func(w http.ResponseWriter, r *http.Request, f url.Values) {
+			m := mail{subject: f.Get("subject")}
+			if m.subject == fail {
+				fmt.Fprintf(w, fakeFzResp, "NG")
+				return
+			}
+			for i := 1; i <= MaxMailFiles; i++ {
+				fhs := r.MultipartForm.File[fmt.Sprintf("file%d", i)]
+				if len(fhs) == 0 {
+					continue
+				}
+				fr, _ := fhs[0].Open()
+				b, _ := ioutil.ReadAll(fr)
+				fr.Close()
+				m.files = append(m.files, fhs[0].Filename)
+				data[fhs[0].Filename] = string(b)
+			}
+			mails = append(mails, m)
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		},
+	})
+	defer ts.Close()
+	fz := &FzAPI{MailLimit: MailLimit{MaxFileSize: 4}}
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	dir, err := ioutil.TempDir("", "fzmailtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	big := filepath.Join(dir, "big.bin")
+	ioutil.WriteFile(big, []byte("0123456789"), 0600)
+	to := []MailAddress{{Email: "to@example.com"}}
+	job := &MailJob{Subject: "S", From: "from@example.com", To: to, Files: []MailFile{{Path: big}}, Days: 1}
+	if err := fz.FzSendMailJob(context.Background(), job); !errors.Is(err, ErrInvalidParam) || len(mails) != 0 {
+		t.Errorf("FzSendMailJob over limit err=%v", err)
+	}
+	job.Split = MailSplitAttachments
+	if err := fz.FzSendMailJob(context.Background(), job); err != nil {
+		t.Fatalf("FzSendMailJob attachments err=%v", err)
+	}
+	if len(mails) != 1 || strings.Join(mails[0].files, ",") != "big.bin.001,big.bin.002,big.bin.003" || mails[0].subject != "S" {
+		t.Errorf("FzSendMailJob attachments mails=%v", mails)
+	}
+	if data["big.bin.001"]+data["big.bin.002"]+data["big.bin.003"] != "0123456789" {
+		t.Errorf("FzSendMailJob attachments data=%v", data)
+	}
+	// ２通に分けて送信する
+	mails = nil
+	fz.MailLimit.MaxTotalSize = 6
+	job.Split = MailSplitMails
+	job.VolumeSize = 3
+	if err := fz.FzSendMailJob(context.Background(), job); err != nil {
+		t.Fatalf("FzSendMailJob mails err=%v", err)
+	}
+	if len(mails) != 2 || mails[0].subject != "S (1/2)" || mails[1].subject != "S (2/2)" || len(mails[0].files) != 2 || len(mails[1].files) != 2 {
+		t.Errorf("FzSendMailJob mails=%v", mails)
+	}
+	// ２通目で失敗した場合は、送信済みの件名を返す
+	mails = nil
+	fail = "S (2/2)"
+	err = fz.FzSendMailJob(context.Background(), job)
+	if err == nil || len(mails) != 1 || !strings.Contains(err.Error(), `mail 2/2`) || !strings.Contains(err.Error(), `sent: ["S (1/2)"]`) {
+		t.Errorf("FzSendMailJob partial mails=%v err=%v", mails, err)
+	}
+	fail = ""
+	// １通に入らない
+	mails = nil
+	job.Split = MailSplitAttachments
+	if err := fz.FzSendMailJob(context.Background(), job); !errors.Is(err, ErrInvalidParam) || len(mails) != 0 {
+		t.Errorf("FzSendMailJob total over err=%v", err)
+	}
+	// 分割できないReader
+	job.Split = MailSplitMails
+	job.Files = []MailFile{{Reader: ioutil.NopCloser(strings.NewReader("0123456789")), Name: "r.bin", Size: 10}}
+	if err := fz.FzSendMailJob(context.Background(), job); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("FzSendMailJob reader err=%v", err)
+	}
+	if _, err := ParseMailSplit("volumes"); err == nil {
+		t.Error("ParseMailSplit no err")
+	}
+	if n, err := ParseSize("200M"); err != nil || n != 200*1024*1024 {
+		t.Errorf("ParseSize n=%d err=%v", n, err)
+	}
+}
+
+// TestMailSchedule : めるあど便の公開開始日時と公開期間の試験
+func TestMailSchedule(t *testing.T) {
+	var form url.Values
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"/mb/cgi-bin/index.cgi/job/api_send/": func(w http.ResponseWriter, r *http.Request, f url.Values) {
+			form = f
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		},
+	})
+	defer ts.Close()
+	fz := &FzAPI{}
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	dir, err := ioutil.TempDir("", "fzmailtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a := filepath.Join(dir, "a.txt")
+	ioutil.WriteFile(a, []byte("aaa"), 0600)
+	mbconf := filepath.Join(dir, "mb.txt")
+	conf := "subject:Test\nmailto:to@example.com\nfrom:from@example.com\nfile:" + a +
+		"\nstart:2030/04/01 09:30\ndays:7\nlimit:1\ncomment:c\n"
+	ioutil.WriteFile(mbconf, []byte(conf), 0600)
+	if err := fz.FzMail(mbconf); err != nil {
+		t.Fatalf("FzMail err=%v", err)
+	}
+	want := map[string]string{
+		"exp_term_start_day":    "1",
+		"exp_term_start_hour":   "09",
+		"exp_term_start_minute": "30",
+		"exp_term_type":         "by_dur",
+		"exp_term_duration":     "7",
+	}
+	for k, v := range want {
+		if form.Get(k) != v {
+			t.Errorf("FzMail %s=%s want=%s", k, form.Get(k), v)
+		}
+	}
+	job, err := LoadMailJob(mbconf)
+	if err != nil {
+		t.Fatalf("LoadMailJob err=%v", err)
+	}
+	job.Days = 0
+	if err := job.Validate(); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("Validate no days err=%v", err)
+	}
+	if _, err := ParseMailTime("2030/04/01 9:30pm"); err == nil {
+		t.Error("ParseMailTime no err")
+	}
+}
@@ -0,0 +1,219 @@
+package fzapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Permission : フォルダのアクセス権限の集合
+type Permission uint
+
+// アクセス権限
+const (
+	PermRead      Permission = 1 << iota // ファイルのダウンロード
+	PermWrite                            // ファイルのアップロード
+	PermDelete                           // ファイルの削除
+	PermOverwrite                        // 同じ名前のファイルの上書き
+	PermList                             // ファイルの一覧
+)
+
+// permissionNames : XMLFolder.Accessの権限の名前
+var permissionNames = []struct {
+	perm Permission
+	name string
+}{
+	{PermRead, "read"},
+	{PermWrite, "write"},
+	{PermDelete, "delete"},
+	{PermOverwrite, "overwrite"},
+	{PermList, "list"},
+}
+
+// PermAll : 全ての権限（Accessの「all」）
+const PermAll = PermRead | PermWrite | PermDelete | PermOverwrite | PermList
+
+// ParsePermission : XMLFolder.Accessの文字列（カンマ、空白または|区切り）を権限の集合に変換する
+// 空の場合や不明な名前を含む場合は、解釈できた名前の権限とfalseを返す
+func ParsePermission(access string) (Permission, bool) {
+	var p Permission
+	f := func(r rune) bool { return r == ',' || r == '|' || r == ' ' }
+	words := strings.FieldsFunc(strings.ToLower(access), f)
+	known := len(words) > 0
+	for _, s := range words {
+		if s == "all" {
+			p |= PermAll
+			continue
+		}
+		found := false
+		for _, n := range permissionNames {
+			if s == n.name {
+				p |= n.perm
+				found = true
+			}
+		}
+		if !found {
+			known = false
+		}
+	}
+	return p, known
+}
+
+// Has : 全ての権限を持つかどうか
+func (p Permission) Has(perm Permission) bool {
+	return p&perm == perm
+}
+
+// CanDownload : ファイルをダウンロードできるかどうか
+func (p Permission) CanDownload() bool {
+	return p.Has(PermRead)
+}
+
+// CanUpload : ファイルをアップロードできるかどうか
+func (p Permission) CanUpload() bool {
+	return p.Has(PermWrite)
+}
+
+// CanDelete : ファイルを削除できるかどうか
+func (p Permission) CanDelete() bool {
+	return p.Has(PermDelete)
+}
+
+// CanOverwrite : 同じ名前のファイルを上書きしてアップロードできるかどうか
+func (p Permission) CanOverwrite() bool {
+	return p.effective().Has(PermOverwrite)
+}
+
+// CanList : ファイルの一覧を参照できるかどうか
+func (p Permission) CanList() bool {
+	return p.effective().Has(PermList)
+}
+
+// effective : 実際に使用できる権限
+// ダウンロードできる場合は一覧も参照でき、アップロードできない場合は上書きもできない
+func (p Permission) effective() Permission {
+	if p.Has(PermRead) {
+		p |= PermList
+	}
+	if !p.Has(PermWrite) {
+		p &^= PermOverwrite
+	}
+	return p
+}
+
+// String : 権限の名前を「,」で連結した文字列
+func (p Permission) String() string {
+	a := []string{}
+	for _, n := range permissionNames {
+		if p&n.perm != 0 {
+			a = append(a, n.name)
+		}
+	}
+	return strings.Join(a, ",")
+}
+
+// FileInfo : FileZenのファイルの情報
+// XMLFileの文字列の項目を変換したもの
+type FileInfo struct {
+	Key     string
+	Name    string
+	Owner   string
+	Size    int64     // バイト
+	ModTime time.Time // 登録日時
+	DRM     bool      // DRMで保護されている
+	PDF     bool      // PDFに変換されている
+}
+
+// Info : ファイルの情報に変換する
+func (f *XMLFile) Info() (*FileInfo, error) {
+	info := &FileInfo{Key: f.Key, Name: f.Name, Owner: f.Owner}
+	var err error
+	if info.Size, err = parseXMLInt(f.Size); err != nil {
+		return nil, fmt.Errorf("File %s - Invalid Size %s: %w", f.Name, f.Size, ErrInvalidParam)
+	}
+	if info.ModTime, err = parseXMLTime(f.TimeStamp); err != nil {
+		return nil, fmt.Errorf("File %s - Invalid TimeStamp %s: %w", f.Name, f.TimeStamp, ErrInvalidParam)
+	}
+	if info.DRM, err = parseXMLFlag(f.DrmFlag); err != nil {
+		return nil, fmt.Errorf("File %s - Invalid DrmFlag %s: %w", f.Name, f.DrmFlag, ErrInvalidParam)
+	}
+	if info.PDF, err = parseXMLFlag(f.PdfFlag); err != nil {
+		return nil, fmt.Errorf("File %s - Invalid PdfFlag %s: %w", f.Name, f.PdfFlag, ErrInvalidParam)
+	}
+	return info, nil
+}
+
+// FolderInfo : FileZenのフォルダの情報
+// XMLFolderの文字列の項目を変換したもの
+type FolderInfo struct {
+	ID         string
+	Name       string
+	Perm       Permission
+	PermKnown  bool  // Accessを全て解釈できた（falseの場合、Permは解釈できた名前の権限だけ）
+	Limit      int64 // 容量の制限（バイト、0は制限なし）
+	Files      []*FileInfo
+	SubFolders []*FolderInfo
+}
+
+// Permissions : フォルダのアクセス権限
+// Accessを解釈できない場合はfalseを返す
+func (d *XMLFolder) Permissions() (Permission, bool) {
+	return ParsePermission(d.Access)
+}
+
+// Info : フォルダの情報（ファイルとサブフォルダを含む）に変換する
+func (d *XMLFolder) Info() (*FolderInfo, error) {
+	info := &FolderInfo{ID: d.ID, Name: d.Name}
+	info.Perm, info.PermKnown = d.Permissions()
+	limit, err := ParseSize(d.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("Folder %s - Invalid Limit %s: %w", d.Name, d.Limit, ErrInvalidParam)
+	}
+	info.Limit = limit
+	for _, f := range d.FileList {
+		fi, err := f.Info()
+		if err != nil {
+			return nil, fmt.Errorf("Folder %s - %w", d.Name, err)
+		}
+		info.Files = append(info.Files, fi)
+	}
+	for _, s := range d.SubFolders {
+		si, err := s.Info()
+		if err != nil {
+			return nil, fmt.Errorf("Folder %s - %w", d.Name, err)
+		}
+		info.SubFolders = append(info.SubFolders, si)
+	}
+	return info, nil
+}
+
+// parseXMLInt : 数値の項目を変換する（空の場合は0）
+func parseXMLInt(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// parseXMLTime : 日時の項目（UNIX時間の秒または2006/01/02 15:04:05）を変換する（空の場合はゼロ）
+func parseXMLTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.ParseInLocation("2006/01/02 15:04:05", s, time.Local)
+}
+
+// parseXMLFlag : フラグの項目（1|0|true|false）を変換する（空の場合はfalse）
+func parseXMLFlag(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "", "0", "false":
+		return false, nil
+	case "1", "true":
+		return true, nil
+	}
+	return false, fmt.Errorf("Invalid flag %s: %w", s, ErrInvalidParam)
+}
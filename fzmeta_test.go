@@ -0,0 +1,95 @@
+package fzapi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFzMeta : ファイルとフォルダの情報の変換の試験
+func TestFzMeta(t *testing.T) {
+	d := &XMLFolder{
+		ID:     "1",
+		Name:   "Folder",
+		Access: "READ, write|list unknown",
+		Limit:  "100M",
+		FileList: []*XMLFile{
+			{Key: "K1", Name: "a.txt", Owner: "admin", Size: "12", TimeStamp: "1577836800", DrmFlag: "1", PdfFlag: "0"},
+			{Key: "K2", Name: "b.txt", TimeStamp: "2020/01/01 09:00:00"},
+		},
+		SubFolders: []*XMLFolder{{ID: "2", Name: "Sub"}},
+	}
+	info, err := d.Info()
+	if err != nil {
+		t.Fatalf("Info err=%v", err)
+	}
+	if info.Perm != PermRead|PermWrite|PermList || info.PermKnown || info.Perm.String() != "read,write,list" || info.Limit != 100*1024*1024 {
+		t.Errorf("Info folder=%+v perm=%s", info, info.Perm)
+	}
+	if !info.Perm.Has(PermRead|PermWrite) || info.Perm.Has(PermWrite|PermDelete) {
+		t.Errorf("Has perm=%s", info.Perm)
+	}
+	if len(info.Files) != 2 || len(info.SubFolders) != 1 || info.SubFolders[0].Perm != 0 || info.SubFolders[0].Limit != 0 {
+		t.Fatalf("Info folder=%+v", info)
+	}
+	a := info.Files[0]
+	if a.Size != 12 || !a.ModTime.Equal(time.Unix(1577836800, 0)) || !a.DRM || a.PDF || a.Owner != "admin" || a.Key != "K1" {
+		t.Errorf("Info file=%+v", a)
+	}
+	if b := info.Files[1]; b.Size != 0 || !b.ModTime.Equal(time.Date(2020, 1, 1, 9, 0, 0, 0, time.Local)) {
+		t.Errorf("Info file=%+v", b)
+	}
+	// 不正な値
+	for _, f := range []*XMLFile{{Size: "12K"}, {TimeStamp: "yesterday"}, {DrmFlag: "yes"}, {PdfFlag: "2"}} {
+		if _, err := f.Info(); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("Info %+v err=%v", f, err)
+		}
+	}
+	d.SubFolders[0].Limit = "many"
+	if _, err := d.Info(); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("Info invalid sub folder limit err=%v", err)
+	}
+}
+
+// TestPermission : アクセス権限の判定の試験
+func TestPermission(t *testing.T) {
+	for access, want := range map[string]string{
+		"":                       "",
+		"read":                   "download,list",
+		"list":                   "list",
+		"read,write":             "download,upload,list",
+		"write,overwrite":        "upload,overwrite",
+		"overwrite":              "",
+		"read,delete":            "download,delete,list",
+		"ALL":                    "download,upload,delete,overwrite,list",
+		"read,write,delete,list": "download,upload,delete,list",
+	} {
+		p, known := ParsePermission(access)
+		if known != (access != "") {
+			t.Errorf("ParsePermission %s known=%v", access, known)
+		}
+		got := []string{}
+		for _, c := range []struct {
+			ok   bool
+			name string
+		}{
+			{p.CanDownload(), "download"},
+			{p.CanUpload(), "upload"},
+			{p.CanDelete(), "delete"},
+			{p.CanOverwrite(), "overwrite"},
+			{p.CanList(), "list"},
+		} {
+			if c.ok {
+				got = append(got, c.name)
+			}
+		}
+		if strings.Join(got, ",") != want {
+			t.Errorf("ParsePermission %s can=%v want=%s", access, got, want)
+		}
+	}
+	// 不明な名前を含む場合
+	if p, known := ParsePermission("read,upload"); known || p != PermRead {
+		t.Errorf("ParsePermission unknown perm=%s known=%v", p, known)
+	}
+}
@@ -0,0 +1,123 @@
+package fzapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+// formField : マルチパートのフィールド
+type formField struct {
+	key   string
+	value string
+}
+
+// formFields : マルチパートのフィールドのリスト（送信順）
+type formFields []formField
+
+// add : フィールドを追加する
+func (ff *formFields) add(key, value string) {
+	*ff = append(*ff, formField{key: key, value: value})
+}
+
+// formFile : マルチパートで送信するファイル
+type formFile struct {
+	key  string
+	name string
+	r    io.Reader
+	size int64 // 不明な場合は-1
+}
+
+// multipartBody : ファイルを含むマルチパートの本文
+// ファイル以外の部分を先に作成し、ファイルの内容はio.Pipeで送信時に読み込む
+type multipartBody struct {
+	contentType string
+	heads       [][]byte // 各ファイルの前に送信する部分
+	tail        []byte   // 最後のファイルの後に送信する部分
+	files       []formFile
+}
+
+// newMultipartBody : マルチパートの本文を作成する（ファイル、フィールドの順に送信する）
+func newMultipartBody(files []formFile, fields formFields) (*multipartBody, error) {
+	buf := &bytes.Buffer{}
+	writer := multipart.NewWriter(buf)
+	mb := &multipartBody{contentType: writer.FormDataContentType(), files: files}
+	for _, f := range files {
+		if _, err := writer.CreateFormFile(f.key, f.name); err != nil {
+			return nil, err
+		}
+		mb.heads = append(mb.heads, append([]byte{}, buf.Bytes()...))
+		buf.Reset()
+	}
+	for _, f := range fields {
+		if err := writer.WriteField(f.key, f.value); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	mb.tail = buf.Bytes()
+	return mb, nil
+}
+
+// contentLength : 本文のサイズ（ファイルのサイズが不明な場合は-1）
+func (mb *multipartBody) contentLength() int64 {
+	n := int64(len(mb.tail))
+	for i, f := range mb.files {
+		if f.size < 0 {
+			return -1
+		}
+		n += int64(len(mb.heads[i])) + f.size
+	}
+	return n
+}
+
+// writeTo : 本文を書き込む
+func (mb *multipartBody) writeTo(w io.Writer) error {
+	for i, f := range mb.files {
+		if _, err := w.Write(mb.heads[i]); err != nil {
+			return err
+		}
+		n, err := io.Copy(w, f.r)
+		if err != nil {
+			return err
+		}
+		if f.size >= 0 && n != f.size {
+			return fmt.Errorf("%s size mismatch %d!=%d", f.name, n, f.size)
+		}
+	}
+	_, err := w.Write(mb.tail)
+	return err
+}
+
+// reader : 本文をio.Pipeで読み込むReaderを返す
+// 読み込み側が閉じられた場合（送信の中止）は、書き込みも終了する
+func (mb *multipartBody) reader() io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(mb.writeTo(pw))
+	}()
+	return pr
+}
+
+// newMultipartRequest : ファイルを含むマルチパートのPOSTリクエストを作成する
+// ファイルの内容をメモリに保持せずに送信する。ファイルのサイズが分かる場合は、Content-Lengthを設定する
+func (fz *FzAPI) newMultipartRequest(ctx context.Context, url string, files []formFile, fields formFields) (*http.Request, error) {
+	mb, err := newMultipartBody(files, fields)
+	if err != nil {
+		return nil, err
+	}
+	body := mb.reader()
+	req, err := fz.newFzRequest(ctx, "POST", url, body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	req.ContentLength = mb.contentLength()
+	req.Header.Set("Content-Type", mb.contentType)
+	return req, nil
+}
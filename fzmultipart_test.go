@@ -0,0 +1,75 @@
+package fzapi
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+// TestMultipartBody : ストリーミングするマルチパートの本文の試験
+func TestMultipartBody(t *testing.T) {
+	data1 := strings.Repeat("a", 100000)
+	data2 := "second file"
+	files := []formFile{
+		{key: "file1", name: "a.txt", r: strings.NewReader(data1), size: int64(len(data1))},
+		{key: "file2", name: "b.txt", r: strings.NewReader(data2), size: int64(len(data2))},
+	}
+	fields := formFields{}
+	fields.add("action", "Mainmenu_upload")
+	fields.add("reg_filename", "テスト.txt")
+	mb, err := newMultipartBody(files, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := mb.reader()
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(body)) != mb.contentLength() {
+		t.Errorf("contentLength %d!=%d", mb.contentLength(), len(body))
+	}
+	_, params, err := mime.ParseMediaType(mb.contentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(1024 * 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []struct{ key, data string }{{"file1", data1}, {"file2", data2}} {
+		fh := form.File[e.key]
+		if len(fh) != 1 {
+			t.Fatalf("%s not found", e.key)
+		}
+		f, _ := fh[0].Open()
+		b, _ := ioutil.ReadAll(f)
+		f.Close()
+		if string(b) != e.data {
+			t.Errorf("%s data mismatch", e.key)
+		}
+	}
+	if form.Value["reg_filename"][0] != "テスト.txt" || form.Value["action"][0] != "Mainmenu_upload" {
+		t.Errorf("fields mismatch %+v", form.Value)
+	}
+	// サイズが異なる場合はエラー
+	files[0].r = strings.NewReader("short")
+	files[1].r = strings.NewReader(data2)
+	if mb, err = newMultipartBody(files, fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(mb.reader()); err == nil {
+		t.Error("size mismatch is no err")
+	}
+	// サイズが不明な場合
+	files[0].size = -1
+	if mb, err = newMultipartBody(files, fields); err != nil {
+		t.Fatal(err)
+	}
+	if mb.contentLength() != -1 {
+		t.Errorf("unknown size contentLength=%d", mb.contentLength())
+	}
+}
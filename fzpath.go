@@ -0,0 +1,157 @@
+package fzapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FzPath : FileZenのプロジェクトとフォルダ（サブフォルダを含む）のパス
+//
+// 文字列の形式は「プロジェクト/フォルダ/サブフォルダ」で、
+// 名前に含まれる「/」と「\」は「\/」、「\\」のようにエスケープする。
+type FzPath struct {
+	Project string
+	Folders []string // フォルダ、サブフォルダの名前（上位から順に）
+}
+
+// ParseFzPath : 「プロジェクト/フォルダ/サブフォルダ」の形式の文字列をFzPathに変換する
+// プロジェクトとフォルダを１つ以上指定する必要がある
+func ParseFzPath(s string) (FzPath, error) {
+	names := []string{}
+	var b strings.Builder
+	esc := false
+	for _, c := range s {
+		switch {
+		case esc:
+			if c != '/' && c != '\\' {
+				return FzPath{}, fmt.Errorf("Invalid escape \\%c in path %s: %w", c, s, ErrInvalidParam)
+			}
+			b.WriteRune(c)
+			esc = false
+		case c == '\\':
+			esc = true
+		case c == '/':
+			names = append(names, b.String())
+			b.Reset()
+		default:
+			b.WriteRune(c)
+		}
+	}
+	if esc {
+		return FzPath{}, fmt.Errorf("Invalid escape at end of path %s: %w", s, ErrInvalidParam)
+	}
+	names = append(names, b.String())
+	if len(names) < 2 {
+		return FzPath{}, fmt.Errorf("No folder in path %s: %w", s, ErrInvalidParam)
+	}
+	for _, n := range names {
+		if n == "" {
+			return FzPath{}, fmt.Errorf("Empty name in path %s: %w", s, ErrInvalidParam)
+		}
+	}
+	return FzPath{Project: names[0], Folders: names[1:]}, nil
+}
+
+// String : エスケープした「プロジェクト/フォルダ/サブフォルダ」の形式の文字列
+func (p FzPath) String() string {
+	a := []string{escapeFzName(p.Project)}
+	for _, f := range p.Folders {
+		a = append(a, escapeFzName(f))
+	}
+	return strings.Join(a, "/")
+}
+
+// escapeFzName : パスの中の名前の「\」と「/」をエスケープする
+func escapeFzName(name string) string {
+	return strings.NewReplacer(`\`, `\\`, `/`, `\/`).Replace(name)
+}
+
+// FindFolder : FileZenのフォルダをパスから探す
+// プロジェクトまたはフォルダが見つからない場合は、ErrNotFoundのエラーを返す
+func (fz *FzAPI) FindFolder(p FzPath) (*XMLFolder, error) {
+	if p.Project == "" || len(p.Folders) < 1 {
+		return nil, fmt.Errorf("FindFolder - Invalid path %s: %w", p, ErrInvalidParam)
+	}
+	var prj *XMLProject
+	for _, xp := range fz.LastResponse().ProjectList {
+		if xp.Name == p.Project {
+			prj = xp
+			break
+		}
+	}
+	if prj == nil {
+		return nil, fmt.Errorf("FindFolder - Project %s not found: %w", p.Project, ErrNotFound)
+	}
+	list := prj.FolderList
+	var folder *XMLFolder
+	for i, name := range p.Folders {
+		folder = nil
+		for _, d := range list {
+			if d.Name == name {
+				folder = d
+				break
+			}
+		}
+		if folder == nil {
+			sub := FzPath{Project: p.Project, Folders: p.Folders[:i+1]}
+			return nil, fmt.Errorf("FindFolder - Folder %s not found: %w", sub, ErrNotFound)
+		}
+		list = folder.SubFolders
+	}
+	return folder, nil
+}
+
+// FindFile : FileZenのフォルダ内のファイルを名前から探す
+// フォルダまたはファイルが見つからない場合は、ErrNotFoundのエラーを返す
+func (fz *FzAPI) FindFile(p FzPath, name string) (*XMLFile, error) {
+	d, err := fz.FindFolder(p)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range d.FileList {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("FindFile - File %s not found in %s: %w", name, p, ErrNotFound)
+}
+
+// CanUploadTo : FileZenのフォルダへファイルをアップロード可能かどうか判断する
+// 同じ名前のファイルがある場合はfalseを返す。
+// フォルダが見つからない場合はErrNotFound、書き込みの権限がない場合はErrPermissionのエラーを返す
+func (fz *FzAPI) CanUploadTo(p FzPath, name string) (bool, error) {
+	if err := fz.CheckPermission(p, PermWrite); err != nil {
+		return false, err
+	}
+	d, err := fz.FindFolder(p)
+	if err != nil {
+		return false, err
+	}
+	for _, f := range d.FileList {
+		if f.Name == name {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// CheckPermission : FileZenのフォルダに指定の権限が全てあるかを確認する
+// フォルダが見つからない場合はErrNotFound、権限が足りない場合は不足する権限の名前を含むErrPermissionのエラーを返す
+// Accessを解釈できない場合は、従来と同じく「write」を含むかでアップロードだけを判定し、それ以外はFileZenの判断に任せる
+func (fz *FzAPI) CheckPermission(p FzPath, perm Permission) error {
+	d, err := fz.FindFolder(p)
+	if err != nil {
+		return err
+	}
+	have, known := d.Permissions()
+	if !known {
+		have = PermAll
+		if !strings.Contains(d.Access, "write") {
+			have &^= PermWrite | PermOverwrite
+		}
+	}
+	if missing := perm &^ have.effective(); missing != 0 {
+		return fmt.Errorf("CheckPermission - No %s access to %s (access=%s): %w", missing, p, d.Access, ErrPermission)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package fzapi
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const fakeProjectResp = `<?xml version="1.0" encoding="UTF-8"?>
+<FileZen>
+<Lastop><Res>OK</Res></Lastop>
+<ProjectList>
+<Project Name="Prj">
+<Folder Name="In/Out" Id="1" Access="read,write">
+<File Name="a.txt" Key="K1"/>
+</Folder>
+<Folder Name="Top" Id="2" Access="read">
+<Folder Name="Sub" Id="3" Access="read,write">
+<File Name="b.txt" Key="K2"/>
+</Folder>
+</Folder>
+</Project>
+</ProjectList>
+</FileZen>`
+
+// TestFzPath : プロジェクト/フォルダのパスの試験
+func TestFzPath(t *testing.T) {
+	for s, want := range map[string]FzPath{
+		"Prj/Folder":    {Project: "Prj", Folders: []string{"Folder"}},
+		`Prj/In\/Out`:   {Project: "Prj", Folders: []string{"In/Out"}},
+		`P\\rj/Top/Sub`: {Project: `P\rj`, Folders: []string{"Top", "Sub"}},
+		"パブリック/パブリック":   {Project: "パブリック", Folders: []string{"パブリック"}},
+	} {
+		p, err := ParseFzPath(s)
+		if err != nil {
+			t.Errorf("ParseFzPath %s err=%v", s, err)
+			continue
+		}
+		if p.Project != want.Project || len(p.Folders) != len(want.Folders) || p.String() != s {
+			t.Errorf("ParseFzPath %s p=%+v", s, p)
+			continue
+		}
+		for i := range p.Folders {
+			if p.Folders[i] != want.Folders[i] {
+				t.Errorf("ParseFzPath %s p=%+v", s, p)
+			}
+		}
+	}
+	for _, s := range []string{"", "Prj", "Prj/", "/Folder", "Prj//Sub", `Prj/F\x`, `Prj/F\`} {
+		if _, err := ParseFzPath(s); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("ParseFzPath %s err=%v", s, err)
+		}
+	}
+	fz := &FzAPI{LastResp: &XMLFileZen{}}
+	if err := xml.Unmarshal([]byte(fakeProjectResp), fz.LastResp); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := ParseFzPath(`Prj/In\/Out`)
+	if d, err := fz.FindFolder(p); err != nil || d.ID != "1" {
+		t.Errorf("FindFolder %s d=%+v err=%v", p, d, err)
+	}
+	sub, _ := ParseFzPath("Prj/Top/Sub")
+	if d, err := fz.FindFolder(sub); err != nil || d.ID != "3" {
+		t.Errorf("FindFolder %s d=%+v err=%v", sub, d, err)
+	}
+	if f, err := fz.FindFile(sub, "b.txt"); err != nil || f.Key != "K2" {
+		t.Errorf("FindFile f=%+v err=%v", f, err)
+	}
+	for _, s := range []string{"Prj/In/Out", "None/Top", "Prj/Top/None"} {
+		np, _ := ParseFzPath(s)
+		if _, err := fz.FindFolder(np); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindFolder %s err=%v", s, err)
+		}
+	}
+	if _, err := fz.FindFile(sub, "c.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindFile not found err=%v", err)
+	}
+	if ok, err := fz.CanUploadTo(p, "new.txt"); !ok || err != nil {
+		t.Errorf("CanUploadTo ok=%v err=%v", ok, err)
+	}
+	if ok, err := fz.CanUploadTo(p, "a.txt"); ok || err != nil {
+		t.Errorf("CanUploadTo exists ok=%v err=%v", ok, err)
+	}
+	top, _ := ParseFzPath("Prj/Top")
+	if _, err := fz.CanUploadTo(top, "new.txt"); !errors.Is(err, ErrPermission) {
+		t.Errorf("CanUploadTo read only err=%v", err)
+	}
+	// 従来の関数
+	if fz.FzFindFolder("Prj/Top/Sub").ID != "3" || fz.FzFindFolder("Prj/None").ID != "" {
+		t.Error("FzFindFolder")
+	}
+	if fz.FzFindFile("Prj", "In/Out", "a.txt") != "K1" || !fz.CanUpload("Prj/Top/Sub", "c.txt") || fz.CanUpload("Prj/Top", "c.txt") {
+		t.Error("FzFindFile or CanUpload")
+	}
+}
+
+// TestCheckPermission : フォルダの権限の確認の試験
+func TestCheckPermission(t *testing.T) {
+	fz := &FzAPI{LastResp: &XMLFileZen{}}
+	if err := xml.Unmarshal([]byte(fakeProjectResp), fz.LastResp); err != nil {
+		t.Fatal(err)
+	}
+	top, _ := ParseFzPath("Prj/Top")
+	if err := fz.CheckPermission(top, PermRead|PermList); err != nil {
+		t.Errorf("CheckPermission read err=%v", err)
+	}
+	err := fz.CheckPermission(top, PermRead|PermWrite|PermDelete)
+	if !errors.Is(err, ErrPermission) || !strings.Contains(err.Error(), "No write,delete access") {
+		t.Errorf("CheckPermission write err=%v", err)
+	}
+	none, _ := ParseFzPath("Prj/None")
+	if err := fz.CheckPermission(none, PermRead); !errors.Is(err, ErrNotFound) {
+		t.Errorf("CheckPermission not found err=%v", err)
+	}
+	// 解釈できないAccessは従来と同じく「write」だけを判定する
+	d, _ := fz.FindFolder(top)
+	for access, canWrite := range map[string]bool{"": false, "download": false, "download,write-only": true} {
+		d.Access = access
+		if err := fz.CheckPermission(top, PermRead|PermDelete); err != nil {
+			t.Errorf("CheckPermission %q read err=%v", access, err)
+		}
+		if err := fz.CheckPermission(top, PermWrite); (err == nil) != canWrite || (err != nil && !errors.Is(err, ErrPermission)) {
+			t.Errorf("CheckPermission %q write err=%v", access, err)
+		}
+	}
+}
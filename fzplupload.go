@@ -0,0 +1,312 @@
+package fzapi
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultChunkSize : 分割アップロードの１回のサイズの既定値
+const DefaultChunkSize = int64(1024 * 1024 * 50) // 50MB
+
+// PlUploadOptions : 分割アップロードの設定
+type PlUploadOptions struct {
+	Retries   int           // １つの分割の送信に失敗した時の再試行回数
+	RetryWait time.Duration // 再試行までの待ち時間（再試行毎に増やす）
+	ChunkSize int64         // 分割のサイズ（0の場合はDefaultChunkSize）
+	Threshold int64         // 分割アップロードにするファイルのサイズ（0の場合はChunkSize）
+	Parallel  int           // 同時に送信する分割の数（0の場合は1）
+}
+
+// chunkSize : 分割のサイズ
+func (o PlUploadOptions) chunkSize() int64 {
+	if o.ChunkSize > 0 {
+		return o.ChunkSize
+	}
+	return DefaultChunkSize
+}
+
+// UsePlUpload : 指定したサイズのファイルを分割アップロードするか判定する
+func (fz *FzAPI) UsePlUpload(size int64) bool {
+	th := fz.PlUpload.Threshold
+	if th <= 0 {
+		th = fz.PlUpload.chunkSize()
+	}
+	return size > th
+}
+
+// PlUploadState : 分割アップロードの途中の状態
+// ファイルに保存して、後から別のプロセスでアップロードを再開できる
+type PlUploadState struct {
+	Fr          string    `json:"fr"`          // 分割アップロードの乱数キー
+	FolderID    string    `json:"folder_id"`   // アップロード先のフォルダID
+	Name        string    `json:"name"`        // FileZen上のファイル名
+	Size        int64     `json:"size"`        // ファイルのサイズ
+	ModTime     time.Time `json:"mod_time"`    // ファイルの更新日時
+	Fingerprint string    `json:"fingerprint"` // ファイルの先頭部分のSHA1
+	ChunkSize   int64     `json:"chunk_size"`  // 分割のサイズ
+	Chunks      int       `json:"chunks"`      // 分割数
+	Done        int       `json:"done"`        // 送信済みの分割数
+}
+
+// newPlUploadState : 新しい分割アップロードの状態を作成する
+func (fz *FzAPI) newPlUploadState(name string, size int64, folderID string) *PlUploadState {
+	st := &PlUploadState{
+		Fr:        secureRandam(10),
+		FolderID:  folderID,
+		Name:      name,
+		Size:      size,
+		ChunkSize: fz.PlUpload.chunkSize(),
+	}
+	st.Chunks = int(size / st.ChunkSize)
+	if size%st.ChunkSize != 0 {
+		st.Chunks++
+	}
+	return st
+}
+
+// matches : 保存した状態が同じファイル、フォルダのアップロードか確認する
+func (st *PlUploadState) matches(o *PlUploadState) bool {
+	return st.FolderID == o.FolderID && st.Name == o.Name && st.Size == o.Size &&
+		st.ModTime.Equal(o.ModTime) && st.Fingerprint == o.Fingerprint &&
+		st.ChunkSize == o.ChunkSize && st.Chunks == o.Chunks &&
+		st.Done >= 0 && st.Done <= st.Chunks
+}
+
+// LoadPlUploadState : 分割アップロードの状態をファイルから読み込む
+func LoadPlUploadState(path string) (*PlUploadState, error) {
+	j, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	st := &PlUploadState{}
+	if err := json.Unmarshal(j, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Save : 分割アップロードの状態をファイルに保存する
+// 途中で中断しても壊れないように、一時ファイルに書き込んでから置き換える
+func (st *PlUploadState) Save(path string) error {
+	j, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, j, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// fileFingerprint : ファイルの先頭部分(1MB)のSHA1
+func fileFingerprint(r io.ReaderAt, size int64) (string, error) {
+	n := size
+	if n > 1024*1024 {
+		n = 1024 * 1024
+	}
+	h := sha1.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, n)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// FzPlUploadResume : 状態をファイルに保存しながら分割アップロードする
+// stateFileに同じファイルの途中の状態があれば、続きから再開する
+// アップロードが完了した場合は、stateFileを削除する
+func (fz *FzAPI) FzPlUploadResume(ctx context.Context, stateFile, localFile, folderID, regName, comment, notifyTo, notifyMode string) error {
+	return fz.FzPlUploadResumeWithOptions(ctx, stateFile, localFile, folderID, NewUploadOptions(regName, comment, notifyTo, notifyMode))
+}
+
+// fzPlUploadResume : FzPlUploadResumeの処理
+func (fz *FzAPI) fzPlUploadResume(ctx context.Context, stateFile, localFile, folderID string, opts UploadOptions) error {
+	file, err := os.Open(localFile)
+	if err != nil {
+		return fmt.Errorf("FzPlUploadResume - os.Open Error: %w", err)
+	}
+	defer file.Close()
+	fstat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("FzPlUploadResume - Stat Error: %w", err)
+	}
+	st := fz.newPlUploadState(filepath.Base(localFile), fstat.Size(), folderID)
+	st.ModTime = fstat.ModTime()
+	st.Fingerprint, err = fileFingerprint(file, st.Size)
+	if err != nil {
+		return fmt.Errorf("FzPlUploadResume - Fingerprint Error: %w", err)
+	}
+	if old, err := LoadPlUploadState(stateFile); err == nil && old.matches(st) {
+		st = old
+	}
+	if err := st.Save(stateFile); err != nil {
+		return fmt.Errorf("FzPlUploadResume - Save State Error: %w", err)
+	}
+	err = fz.plUpload(ctx, file, file, st, stateFile, opts)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("FzPlUploadResume - Remove State Error: %w", err)
+	}
+	return nil
+}
+
+// chunkRange : 分割のファイルの先頭からの位置とサイズ
+func (st *PlUploadState) chunkRange(chunk int) (int64, int64) {
+	off := int64(chunk-1) * st.ChunkSize
+	n := st.ChunkSize
+	if st.Size-off < n {
+		n = st.Size - off
+	}
+	return off, n
+}
+
+// plUpload : 状態の続きから分割アップロードする
+// raを指定した場合は、分割毎にraのファイルの先頭からの位置で読み込む（再開、再試行、並列送信ができる）
+// stateFileが指定された場合は、分割を送信する毎に状態を保存する
+func (fz *FzAPI) plUpload(ctx context.Context, file io.Reader, ra io.ReaderAt, st *PlUploadState, stateFile string, opts UploadOptions) error {
+	if st.Done > 0 && ra == nil {
+		return fmt.Errorf("FzPlUpload - resume needs io.ReaderAt: %w", ErrInvalidParam)
+	}
+	pr := fz.newProgress(ctx, st.Name, st.Size)
+	pr.resume(int64(st.Done)*st.ChunkSize, st.Chunks)
+	defer pr.finish()
+	th := fz.newThrottle(ctx)
+	if ra != nil && fz.PlUpload.Parallel > 1 {
+		if err := fz.plUploadParallel(ctx, ra, st, stateFile, fz.PlUpload.Parallel, pr, th); err != nil {
+			return err
+		}
+		return fz.fzPlUploadDone(ctx, st.Fr, st.Name, st.FolderID, opts)
+	}
+	for chunk := st.Done + 1; chunk <= st.Chunks; chunk++ {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("FzPlUpload - canceled: %w", err)
+		}
+		off, nSend := st.chunkRange(chunk)
+		if err := fz.plUploadChunk(ctx, file, ra, off, nSend, st, chunk, pr, th); err != nil {
+			return err
+		}
+		st.Done = chunk
+		if stateFile != "" {
+			if err := st.Save(stateFile); err != nil {
+				return fmt.Errorf("FzPlUpload - Save State Error: %w", err)
+			}
+		}
+	}
+	return fz.fzPlUploadDone(ctx, st.Fr, st.Name, st.FolderID, opts)
+}
+
+// plUploadParallel : 複数の分割を同時に送信する
+// 状態には、先頭から続けて送信が完了した分割の数を保存する
+// １つでも失敗した場合は、残りの送信を中止する
+func (fz *FzAPI) plUploadParallel(ctx context.Context, ra io.ReaderAt, st *PlUploadState, stateFile string, parallel int, pr *progress, th *throttle) error {
+	type result struct {
+		chunk int
+		err   error
+	}
+	pctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	next := make(chan int)
+	res := make(chan result)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range next {
+				off, nSend := st.chunkRange(chunk)
+				res <- result{chunk: chunk, err: fz.plUploadChunk(pctx, nil, ra, off, nSend, st, chunk, pr, th)}
+			}
+		}()
+	}
+	go func() {
+		defer close(next)
+		for chunk := st.Done + 1; chunk <= st.Chunks; chunk++ {
+			select {
+			case next <- chunk:
+			case <-pctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(res)
+	}()
+	var err error
+	sent := map[int]bool{}
+	for r := range res {
+		if err != nil {
+			continue
+		}
+		if r.err != nil {
+			err = r.err
+			cancel()
+			continue
+		}
+		sent[r.chunk] = true
+		done := st.Done
+		for sent[st.Done+1] {
+			delete(sent, st.Done+1)
+			st.Done++
+		}
+		if stateFile != "" && st.Done != done {
+			if e := st.Save(stateFile); e != nil {
+				err = fmt.Errorf("FzPlUpload - Save State Error: %w", e)
+				cancel()
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if e := ctx.Err(); e != nil {
+		return fmt.Errorf("FzPlUpload - canceled: %w", e)
+	}
+	return nil
+}
+
+// plUploadChunk : １つの分割を送信する
+// raを指定した場合は、失敗した時にPlUpload.Retriesの回数まで再試行する
+func (fz *FzAPI) plUploadChunk(ctx context.Context, file io.Reader, ra io.ReaderAt, off, nSend int64, st *PlUploadState, chunk int, pr *progress, th *throttle) error {
+	pr.setChunk(chunk)
+	for retry := 0; ; retry++ {
+		src := file
+		if ra != nil {
+			src = io.NewSectionReader(ra, off, nSend)
+		}
+		cr := &progressReader{r: th.reader(src), pr: pr}
+		_, err := fz.fzPlUploadPart(ctx, cr, nSend, st.Fr, st.FolderID, chunk, st.Chunks)
+		if err == nil {
+			return nil
+		}
+		// 送信し直す分を進捗から戻す
+		pr.add(-cr.count())
+		if ra == nil || retry >= fz.PlUpload.Retries || !isRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("FzPlUpload - canceled: %w", ctx.Err())
+		case <-time.After(fz.PlUpload.RetryWait * time.Duration(retry+1)):
+		}
+	}
+}
+
+// isRetryable : 再試行で回復する可能性があるエラーか判定する
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return errors.Is(err, ErrNetwork) || errors.Is(err, ErrServer)
+}
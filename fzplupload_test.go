@@ -0,0 +1,193 @@
+package fzapi
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// fakePlUpload : 分割アップロードを受信する試験用のサーバー
+type fakePlUpload struct {
+	mu     sync.Mutex
+	parts  map[string]map[int][]byte // 受信したファイル名毎の分割
+	fail   map[int]int               // 分割毎に失敗させる回数
+	ukeys  map[int]string            // 分割毎に受信したukey
+	posted int                       // 送信された回数
+	done   string
+}
+
+func newFakePlUpload() *fakePlUpload {
+	return &fakePlUpload{parts: map[string]map[int][]byte{}, fail: map[int]int{}, ukeys: map[int]string{}}
+}
+
+func (p *fakePlUpload) handlers() map[string]fakeFzHandler {
+	return map[string]fakeFzHandler{
+		"Mainmenu_upload/plupload": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.posted++
+			chunk, _ := strconv.Atoi(form.Get("chunk"))
+			if p.fail[chunk] > 0 {
+				p.fail[chunk]--
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fh := r.MultipartForm.File["file"][0]
+			f, _ := fh.Open()
+			b, _ := ioutil.ReadAll(f)
+			f.Close()
+			if p.parts[fh.Filename] == nil {
+				p.parts[fh.Filename] = map[int][]byte{}
+			}
+			p.parts[fh.Filename][chunk] = b
+			p.ukeys[chunk] = form.Get("ukey")
+		},
+		"Mainmenu_upload/do_upload": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			// 分割はfrとフォルダIDのファイル名で受信しているものを結合する
+			parts := p.parts[form.Get("fr")+form.Get("ST_current_folder")+".tmp"]
+			if parts == nil || len(p.parts) != 1 {
+				fmt.Fprintf(w, fakeFzResp, "NG")
+				return
+			}
+			for i := 1; i <= len(parts); i++ {
+				p.done += string(parts[i])
+			}
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		},
+	}
+}
+
+// TestFzPlUploadRetry : 分割アップロードの再試行の試験
+func TestFzPlUploadRetry(t *testing.T) {
+	p := newFakePlUpload()
+	p.fail[2] = 2
+	ts := newFakeFz(t, p.handlers())
+	defer ts.Close()
+	fz := &FzAPI{}
+	fz.PlUpload.ChunkSize = 4
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	local := filepath.Join("testdata", "test.txt")
+	fz.PlUpload.Retries = 1
+	if err := fz.FzPlUpload(local, "1", "test.txt", "test", "", ""); err == nil {
+		t.Error("FzPlUpload no err after retries")
+	}
+	p = newFakePlUpload()
+	p.fail[2] = 2
+	ts2 := newFakeFz(t, p.handlers())
+	defer ts2.Close()
+	if err := fz.FzLogin(ts2.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	fz.PlUpload.Retries = 2
+	if err := fz.FzPlUpload(local, "1", "test.txt", "test", "", ""); err != nil {
+		t.Fatalf("FzPlUpload err=%v", err)
+	}
+	b, _ := ioutil.ReadFile(local)
+	if p.done != string(b) {
+		t.Errorf("FzPlUpload data=%q", p.done)
+	}
+	if p.posted != 5 {
+		t.Errorf("FzPlUpload posted=%d", p.posted)
+	}
+}
+
+// TestFzPlUploadResume : 中断した分割アップロードの再開の試験
+func TestFzPlUploadResume(t *testing.T) {
+	p := newFakePlUpload()
+	p.fail[3] = 1
+	handlers := p.handlers()
+	// ログイン毎に別のセッションにする
+	logins := 0
+	handlers["Login/auth"] = func(w http.ResponseWriter, r *http.Request, form url.Values) {
+		logins++
+		http.SetCookie(w, &http.Cookie{Name: "SessionID", Value: fmt.Sprintf("sid%d", logins)})
+		fmt.Fprintf(w, fakeFzResp, "OK")
+	}
+	ts := newFakeFz(t, handlers)
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "fzplupload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "test.txt.plupload")
+	local := filepath.Join("testdata", "test.txt")
+	fz := &FzAPI{}
+	fz.PlUpload.ChunkSize = 4
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	if err := fz.FzPlUploadResume(context.Background(), stateFile, local, "1", "test.txt", "test", "", ""); err == nil {
+		t.Fatal("FzPlUploadResume no err")
+	}
+	st, err := LoadPlUploadState(stateFile)
+	if err != nil {
+		t.Fatalf("LoadPlUploadState err=%v", err)
+	}
+	if st.Done != 2 || st.Chunks != 3 {
+		t.Errorf("LoadPlUploadState done=%d chunks=%d", st.Done, st.Chunks)
+	}
+	// 別のプロセスから再開する
+	fz2 := &FzAPI{}
+	fz2.PlUpload.ChunkSize = 4
+	if err := fz2.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	if err := fz2.FzPlUploadResume(context.Background(), stateFile, local, "1", "test.txt", "test", "", ""); err != nil {
+		t.Fatalf("FzPlUploadResume err=%v", err)
+	}
+	b, _ := ioutil.ReadFile(local)
+	if p.done != string(b) {
+		t.Errorf("FzPlUploadResume data=%q", p.done)
+	}
+	if p.posted != 4 {
+		t.Errorf("FzPlUploadResume posted=%d", p.posted)
+	}
+	if _, err := os.Stat(stateFile); err == nil {
+		t.Error("FzPlUploadResume state file remains")
+	}
+	// ukeyはセッション毎に変わるが、分割のファイル名は同じ
+	if p.ukeys[1] != "sid11" || p.ukeys[3] != "sid21" {
+		t.Errorf("FzPlUploadResume ukeys=%v", p.ukeys)
+	}
+	if _, ok := p.parts[st.Fr+"1.tmp"]; !ok || len(p.parts) != 1 {
+		t.Errorf("FzPlUploadResume part names=%v", p.parts)
+	}
+}
+
+// TestFzPlUploadParallel : 分割の並列送信の試験
+func TestFzPlUploadParallel(t *testing.T) {
+	p := newFakePlUpload()
+	ts := newFakeFz(t, p.handlers())
+	defer ts.Close()
+	fz := &FzAPI{}
+	fz.PlUpload.ChunkSize = 1
+	fz.PlUpload.Parallel = 4
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	local := filepath.Join("testdata", "test.txt")
+	if err := fz.FzPlUpload(local, "1", "test.txt", "test", "", ""); err != nil {
+		t.Fatalf("FzPlUpload err=%v", err)
+	}
+	b, _ := ioutil.ReadFile(local)
+	if p.done != string(b) {
+		t.Errorf("FzPlUpload data=%q", p.done)
+	}
+	if !fz.UsePlUpload(2) || fz.UsePlUpload(1) {
+		t.Error("UsePlUpload threshold error")
+	}
+	fz.PlUpload.Threshold = 100
+	if fz.UsePlUpload(100) || !fz.UsePlUpload(101) {
+		t.Error("UsePlUpload threshold error")
+	}
+}
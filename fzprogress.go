@@ -0,0 +1,175 @@
+package fzapi
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressInterval : 進捗を通知する間隔
+const progressInterval = 200 * time.Millisecond
+
+// Progress : 転送の進捗
+type Progress struct {
+	Name     string        // 転送するファイル名
+	Done     int64         // 転送済みのバイト数
+	Total    int64         // 全体のバイト数（不明な場合は-1）
+	Chunk    int           // 送信中の分割（分割アップロード以外は0）
+	Chunks   int           // 分割数（分割アップロード以外は0）
+	Rate     float64       // 転送速度(バイト/秒)
+	ETA      time.Duration // 残り時間の予想（不明な場合は-1）
+	Elapsed  time.Duration // 経過時間
+	Finished bool          // 転送の終了（エラーの場合も含む）
+}
+
+// ProgressObserver : 転送の進捗を受け取る
+// 並行して転送する場合は、複数のgoroutineから呼ばれる
+type ProgressObserver interface {
+	OnProgress(p Progress)
+}
+
+// ProgressFunc : 関数をProgressObserverとして使用する
+type ProgressFunc func(p Progress)
+
+// OnProgress : 関数を呼び出す
+func (f ProgressFunc) OnProgress(p Progress) {
+	f(p)
+}
+
+// progress : １つの転送の進捗を集計する
+// ProgressObserverが設定されていない場合はnilで、全てのメソッドは何もしない
+type progress struct {
+	obs   ProgressObserver
+	mu    sync.Mutex
+	p     Progress
+	base  int64 // 再開した場合の転送済みのバイト数
+	start time.Time
+	last  time.Time
+}
+
+// newProgress : 転送の進捗の集計を開始する
+func (fz *FzAPI) newProgress(ctx context.Context, name string, total int64) *progress {
+	if fz.Progress == nil {
+		return nil
+	}
+	if n, ok := ctx.Value(progressNameKey{}).(string); ok {
+		name = n
+	}
+	return &progress{obs: fz.Progress, p: Progress{Name: name, Total: total, ETA: -1}, start: time.Now()}
+}
+
+// resume : 再開した場合の転送済みのバイト数と分割数を設定する
+func (pr *progress) resume(done int64, chunks int) {
+	if pr == nil {
+		return
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.p.Done, pr.base, pr.p.Chunks = done, done, chunks
+}
+
+// add : 転送したバイト数を加える（再試行で送信し直す場合は負の値）
+func (pr *progress) add(n int64) {
+	if pr == nil {
+		return
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.p.Done += n
+	if time.Since(pr.last) >= progressInterval {
+		pr.notify()
+	}
+}
+
+// setChunk : 送信中の分割を設定する
+func (pr *progress) setChunk(chunk int) {
+	if pr == nil {
+		return
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.p.Chunk = chunk
+	pr.notify()
+}
+
+// finish : 転送の終了を通知する
+func (pr *progress) finish() {
+	if pr == nil {
+		return
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.p.Finished = true
+	pr.notify()
+}
+
+// notify : 速度と残り時間を計算して通知する（ロックして呼び出す）
+func (pr *progress) notify() {
+	pr.last = time.Now()
+	pr.p.Elapsed = pr.last.Sub(pr.start)
+	if s := pr.p.Elapsed.Seconds(); s > 0 {
+		pr.p.Rate = float64(pr.p.Done-pr.base) / s
+	}
+	pr.p.ETA = -1
+	if pr.p.Total >= 0 && pr.p.Rate > 0 {
+		pr.p.ETA = time.Duration(float64(pr.p.Total-pr.p.Done) / pr.p.Rate * float64(time.Second))
+	}
+	pr.obs.OnProgress(pr.p)
+}
+
+// reader : 読み込んだバイト数を集計するReader
+func (pr *progress) reader(r io.Reader) io.Reader {
+	if pr == nil {
+		return r
+	}
+	return &progressReader{r: r, pr: pr}
+}
+
+// writer : 書き込んだバイト数を集計するWriter
+func (pr *progress) writer(w io.Writer) io.Writer {
+	if pr == nil {
+		return w
+	}
+	return &progressWriter{w: w, pr: pr}
+}
+
+// progressReader : 読み込んだバイト数を集計するReader
+// 送信中のgoroutineから読み込まれるため、nはatomicで更新する
+type progressReader struct {
+	n  int64 // 読み込んだバイト数（64bitのアラインメントのため先頭に置く）
+	r  io.Reader
+	pr *progress
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	atomic.AddInt64(&r.n, int64(n))
+	r.pr.add(int64(n))
+	return n, err
+}
+
+// count : 読み込んだバイト数
+func (r *progressReader) count() int64 {
+	return atomic.LoadInt64(&r.n)
+}
+
+// progressWriter : 書き込んだバイト数を集計するWriter
+type progressWriter struct {
+	w  io.Writer
+	pr *progress
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.pr.add(int64(n))
+	return n, err
+}
+
+type progressNameKey struct{}
+
+// withProgressName : 進捗に表示するファイル名をコンテキストに保存する
+func withProgressName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, progressNameKey{}, name)
+}
@@ -0,0 +1,70 @@
+package fzapi
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// progressLog : 受け取った進捗を記録する
+type progressLog struct {
+	mu   sync.Mutex
+	list []Progress
+}
+
+func (l *progressLog) OnProgress(p Progress) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.list = append(l.list, p)
+}
+
+func (l *progressLog) last() Progress {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.list) < 1 {
+		return Progress{}
+	}
+	return l.list[len(l.list)-1]
+}
+
+// TestFzProgress : 転送の進捗の試験
+func TestFzProgress(t *testing.T) {
+	p := newFakePlUpload()
+	p.fail[2] = 1
+	handlers := p.handlers()
+	handlers["Mainmenu_file/download"] = func(w http.ResponseWriter, r *http.Request, form url.Values) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(form.Get("key"))))
+		w.Write([]byte(form.Get("key")))
+	}
+	ts := newFakeFz(t, handlers)
+	defer ts.Close()
+	l := &progressLog{}
+	fz := &FzAPI{Progress: l}
+	fz.PlUpload.ChunkSize = 4
+	fz.PlUpload.Retries = 1
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	if err := fz.FzPlUpload(filepath.Join("testdata", "test.txt"), "1", "test.txt", "test", "", ""); err != nil {
+		t.Fatalf("FzPlUpload err=%v", err)
+	}
+	e := l.last()
+	if !e.Finished || e.Name != "test.txt" || e.Done != 11 || e.Total != 11 || e.Chunk != 3 || e.Chunks != 3 {
+		t.Errorf("FzPlUpload progress=%+v", e)
+	}
+	l = &progressLog{}
+	fz.Progress = ProgressFunc(l.OnProgress)
+	w := &bytes.Buffer{}
+	if err := fz.FzDownloadWriter(context.Background(), "0123456789", w); err != nil {
+		t.Fatalf("FzDownloadWriter err=%v", err)
+	}
+	e = l.last()
+	if !e.Finished || e.Name != "0123456789" || e.Done != 10 || e.Total != 10 {
+		t.Errorf("FzDownloadWriter progress=%+v", e)
+	}
+}
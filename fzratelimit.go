@@ -0,0 +1,274 @@
+package fzapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitBlock : 転送速度を制限する場合の１回の読み書きのサイズ
+const rateLimitBlock = 32 * 1024
+
+// RateLimit : 転送速度の制限(バイト/秒、0は無制限)
+type RateLimit struct {
+	BytesPerSec int64          // 全ての転送の合計の上限
+	PerTransfer int64          // １つの転送の上限
+	Schedule    []RateSchedule // 時間帯毎の制限（一致した時間帯があれば、そちらを使用する）
+}
+
+// RateSchedule : 時間帯毎の転送速度の制限
+type RateSchedule struct {
+	Start       time.Duration // 開始時刻（0時からの時間）
+	End         time.Duration // 終了時刻（Startより前の場合は、日をまたぐ）
+	BytesPerSec int64         // 全ての転送の合計の上限
+	PerTransfer int64         // １つの転送の上限
+}
+
+// contains : 時刻が時間帯に含まれるか判定する
+func (s RateSchedule) contains(t time.Time) bool {
+	d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if s.Start <= s.End {
+		return s.Start <= d && d < s.End
+	}
+	return s.Start <= d || d < s.End
+}
+
+// current : 時刻に対応する全体と１つの転送の上限
+func (rl RateLimit) current(t time.Time) (int64, int64) {
+	for _, s := range rl.Schedule {
+		if s.contains(t) {
+			return s.BytesPerSec, s.PerTransfer
+		}
+	}
+	return rl.BytesPerSec, rl.PerTransfer
+}
+
+// ParseRate : 転送速度の文字列(例: 512K, 10M, 1G)をバイト/秒にする
+// 空の場合は0（無制限）
+func ParseRate(s string) (int64, error) {
+	n, ok := parseBytes(s)
+	if !ok {
+		return 0, fmt.Errorf("Invalid rate %s: %w", s, ErrInvalidParam)
+	}
+	return n, nil
+}
+
+// ParseSize : サイズの文字列(例: 512K, 10M, 1G)をバイト数にする
+// 空の場合は0
+func ParseSize(s string) (int64, error) {
+	n, ok := parseBytes(s)
+	if !ok {
+		return 0, fmt.Errorf("Invalid size %s: %w", s, ErrInvalidParam)
+	}
+	return n, nil
+}
+
+// parseBytes : K、M、Gの単位付きの数値を変換する
+func parseBytes(s string) (int64, bool) {
+	s = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B")
+	if s == "" {
+		return 0, true
+	}
+	m := int64(1)
+	switch s[len(s)-1] {
+	case 'K':
+		m = 1024
+	case 'M':
+		m = 1024 * 1024
+	case 'G':
+		m = 1024 * 1024 * 1024
+	}
+	if m > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n * m, true
+}
+
+// ParseRateSchedule : 時間帯毎の制限の文字列を解析する
+// 書式は「開始-終了=全体の上限/１つの転送の上限」（例: 09:00-18:00=1M/256K）
+// １つの転送の上限は省略できる
+func ParseRateSchedule(s string) (RateSchedule, error) {
+	rs := RateSchedule{}
+	a := strings.SplitN(s, "=", 2)
+	t := strings.SplitN(a[0], "-", 2)
+	if len(a) != 2 || len(t) != 2 {
+		return rs, fmt.Errorf("Invalid rate schedule %s: %w", s, ErrInvalidParam)
+	}
+	var err error
+	if rs.Start, err = parseClock(t[0]); err != nil {
+		return rs, err
+	}
+	if rs.End, err = parseClock(t[1]); err != nil {
+		return rs, err
+	}
+	r := strings.SplitN(a[1], "/", 2)
+	if rs.BytesPerSec, err = ParseRate(r[0]); err != nil {
+		return rs, err
+	}
+	if len(r) > 1 {
+		if rs.PerTransfer, err = ParseRate(r[1]); err != nil {
+			return rs, err
+		}
+	}
+	return rs, nil
+}
+
+// parseClock : 時刻(15:04)を0時からの時間にする
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("Invalid time %s: %w", s, ErrInvalidParam)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// limiter : トークンバケットによる転送速度の制限
+type limiter struct {
+	mu     sync.Mutex
+	rate   func() int64 // 現在の上限（0は無制限）
+	tokens float64
+	last   time.Time
+}
+
+// newLimiter : 上限を返す関数から制限を作成する
+func newLimiter(rate func() int64) *limiter {
+	return &limiter{rate: rate, last: time.Now()}
+}
+
+// wait : nバイトを転送できるまで待つ
+func (l *limiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	rate := l.rate()
+	if rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * float64(rate)
+	if l.tokens > float64(rate) {
+		// 最大で１秒分まで貯める
+		l.tokens = float64(rate)
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	d := time.Duration(-l.tokens / float64(rate) * float64(time.Second))
+	l.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// throttle : １つの転送の速度を制限する
+type throttle struct {
+	ctx    context.Context
+	global *limiter
+	local  *limiter
+}
+
+// newThrottle : 転送毎の速度の制限を作成する（制限がない場合はnil）
+func (fz *FzAPI) newThrottle(ctx context.Context) *throttle {
+	if fz.RateLimit.BytesPerSec <= 0 && fz.RateLimit.PerTransfer <= 0 && len(fz.RateLimit.Schedule) == 0 {
+		return nil
+	}
+	fz.limitOnce.Do(func() {
+		fz.limiter = newLimiter(func() int64 {
+			g, _ := fz.RateLimit.current(time.Now())
+			return g
+		})
+	})
+	return &throttle{
+		ctx:    ctx,
+		global: fz.limiter,
+		local: newLimiter(func() int64 {
+			_, l := fz.RateLimit.current(time.Now())
+			return l
+		}),
+	}
+}
+
+// wait : nバイトを転送できるまで待つ
+func (t *throttle) wait(n int) error {
+	if err := t.global.wait(t.ctx, n); err != nil {
+		return err
+	}
+	return t.local.wait(t.ctx, n)
+}
+
+// reader : 読み込みの速度を制限するReader
+func (t *throttle) reader(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &throttleReader{r: r, t: t}
+}
+
+// writer : 書き込みの速度を制限するWriter
+func (t *throttle) writer(w io.Writer) io.Writer {
+	if t == nil {
+		return w
+	}
+	return &throttleWriter{w: w, t: t}
+}
+
+// throttleReader : 読み込みの速度を制限するReader
+type throttleReader struct {
+	r io.Reader
+	t *throttle
+}
+
+func (r *throttleReader) Read(p []byte) (int, error) {
+	if len(p) > rateLimitBlock {
+		p = p[:rateLimitBlock]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.t.wait(n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// throttleWriter : 書き込みの速度を制限するWriter
+type throttleWriter struct {
+	w io.Writer
+	t *throttle
+}
+
+func (w *throttleWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		b := p
+		if len(b) > rateLimitBlock {
+			b = b[:rateLimitBlock]
+		}
+		if err := w.t.wait(len(b)); err != nil {
+			return written, err
+		}
+		n, err := w.w.Write(b)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,81 @@
+package fzapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRateLimitParse : 転送速度の制限の設定の試験
+func TestRateLimitParse(t *testing.T) {
+	rates := map[string]int64{"": 0, "100": 100, "512K": 512 * 1024, "10m": 10 * 1024 * 1024, "1GB": 1024 * 1024 * 1024}
+	for s, want := range rates {
+		if got, err := ParseRate(s); err != nil || got != want {
+			t.Errorf("ParseRate(%q)=%d err=%v", s, got, err)
+		}
+	}
+	if _, err := ParseRate("fast"); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("ParseRate invalid err=%v", err)
+	}
+	rs, err := ParseRateSchedule("22:00-06:30=0/1M")
+	if err != nil {
+		t.Fatalf("ParseRateSchedule err=%v", err)
+	}
+	if rs.Start != 22*time.Hour || rs.End != 6*time.Hour+30*time.Minute || rs.BytesPerSec != 0 || rs.PerTransfer != 1024*1024 {
+		t.Errorf("ParseRateSchedule=%+v", rs)
+	}
+	if _, err := ParseRateSchedule("09:00=1M"); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("ParseRateSchedule invalid err=%v", err)
+	}
+	rl := RateLimit{BytesPerSec: 100, PerTransfer: 10, Schedule: []RateSchedule{rs}}
+	day := func(h, m int) time.Time { return time.Date(2020, 4, 1, h, m, 0, 0, time.Local) }
+	if g, l := rl.current(day(23, 0)); g != 0 || l != 1024*1024 {
+		t.Errorf("RateLimit 23:00 = %d %d", g, l)
+	}
+	if g, l := rl.current(day(6, 0)); g != 0 || l != 1024*1024 {
+		t.Errorf("RateLimit 06:00 = %d %d", g, l)
+	}
+	if g, l := rl.current(day(12, 0)); g != 100 || l != 10 {
+		t.Errorf("RateLimit 12:00 = %d %d", g, l)
+	}
+}
+
+// TestRateLimitDownload : ダウンロードの転送速度の制限の試験
+func TestRateLimitDownload(t *testing.T) {
+	data := strings.Repeat("x", 32*1024)
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"Mainmenu_file/download": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.Write([]byte(data))
+		},
+	})
+	defer ts.Close()
+	fz := &FzAPI{}
+	fz.RateLimit.PerTransfer = 64 * 1024
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	st := time.Now()
+	w := &bytes.Buffer{}
+	if err := fz.FzDownloadWriter(context.Background(), "k1", w); err != nil {
+		t.Fatalf("FzDownloadWriter err=%v", err)
+	}
+	if w.String() != data {
+		t.Error("FzDownloadWriter data mismatch")
+	}
+	if d := time.Since(st); d < 400*time.Millisecond {
+		t.Errorf("FzDownloadWriter not limited %v", d)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	fz.RateLimit.PerTransfer = 1024
+	if err := fz.FzDownloadWriter(ctx, "k1", &bytes.Buffer{}); err == nil {
+		t.Error("FzDownloadWriter canceled no err")
+	}
+}
@@ -0,0 +1,80 @@
+package fzapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CredentialProvider : 再ログインに使用するユーザーIDとパスワードを返す
+type CredentialProvider func(ctx context.Context) (uid, password string, err error)
+
+// StaticCredentials : 固定のユーザーIDとパスワードを返すCredentialProviderを作成する
+func StaticCredentials(uid, password string) CredentialProvider {
+	return func(ctx context.Context) (string, string, error) {
+		return uid, password, nil
+	}
+}
+
+// ReloginEvent : 再ログインの通知内容
+type ReloginEvent struct {
+	Time  time.Time // 再ログインした日時
+	Cause error     // 再ログインの原因になったエラー
+	Err   error     // 再ログインのエラー(成功した場合はnil)
+}
+
+// FzLoginWithCredentials : CredentialProviderを使ってログインする
+// セッションの期限が切れた場合は、同じCredentialProviderで自動的に再ログインする
+func (fz *FzAPI) FzLoginWithCredentials(ctx context.Context, fzURL string, cred CredentialProvider) error {
+	if cred == nil {
+		return fmt.Errorf("FzLoginWithCredentials - no credentials: %w", ErrInvalidParam)
+	}
+	fz.Credentials = cred
+	uid, password, err := cred(ctx)
+	if err != nil {
+		return fmt.Errorf("FzLoginWithCredentials - credentials Error: %w", err)
+	}
+	return fz.FzLoginContext(ctx, fzURL, uid, password)
+}
+
+// relogin : 再ログインする
+// 複数のgoroutineが同時に期限切れを検出した場合は、最初の１つだけが再ログインする
+func (fz *FzAPI) relogin(ctx context.Context, cause error, sid string) error {
+	fz.reloginMu.Lock()
+	defer fz.reloginMu.Unlock()
+	if fz.session().Value != sid {
+		// 他のgoroutineが再ログイン済み
+		return nil
+	}
+	uid, password, err := fz.Credentials(ctx)
+	if err == nil {
+		err = fz.FzLoginContext(ctx, fz.baseURL(), uid, password)
+	}
+	if err != nil {
+		fz.log(LogError, "relogin failed", LogFields{"cause": cause.Error(), "error": err.Error()})
+	} else {
+		fz.log(LogInfo, "relogin", LogFields{"cause": cause.Error()})
+	}
+	if fz.OnRelogin != nil {
+		fz.OnRelogin(ReloginEvent{Time: time.Now(), Cause: cause, Err: err})
+	}
+	return err
+}
+
+// withRelogin : セッションの期限切れの場合に再ログインする
+// replayがtrue(冪等なリクエスト)の場合は、１回だけ再実行する
+func (fz *FzAPI) withRelogin(ctx context.Context, replay bool, op func() error) error {
+	sid := fz.session().Value
+	err := op()
+	if err == nil || fz.Credentials == nil || !errors.Is(err, ErrSessionExpired) || ctx.Err() != nil {
+		return err
+	}
+	if rerr := fz.relogin(ctx, err, sid); rerr != nil {
+		return fmt.Errorf("%w (relogin Error: %v)", err, rerr)
+	}
+	if !replay {
+		return err
+	}
+	return op()
+}
@@ -0,0 +1,70 @@
+package fzapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// TestFzRelogin : セッションの期限切れ時の再ログインの試験
+// 期限切れのセッションはログイン画面にリダイレクトされる
+func TestFzRelogin(t *testing.T) {
+	logins := 0
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"Login/auth": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			logins++
+			http.SetCookie(w, &http.Cookie{Name: "SessionID", Value: fmt.Sprintf("sid%d", logins)})
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		},
+		"Mainmenu_file/show": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			if c, err := r.Cookie("SessionID"); err != nil || c.Value != fmt.Sprintf("sid%d", logins) || logins < 2 {
+				http.Redirect(w, r, "/login.html", http.StatusFound)
+				return
+			}
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		},
+		"/": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			fmt.Fprint(w, "<html><body>login</body></html>")
+		},
+		"Mainmenu_file/delete_file": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			fmt.Fprint(w, "<html><body>error</body></html>")
+		},
+	})
+	defer ts.Close()
+	fz := &FzAPI{}
+	events := []ReloginEvent{}
+	fz.OnRelogin = func(e ReloginEvent) {
+		events = append(events, e)
+	}
+	if err := fz.FzLoginWithCredentials(context.Background(), ts.URL, StaticCredentials("admin", "admin")); err != nil {
+		t.Fatalf("FzLoginWithCredentials err=%v", err)
+	}
+	if err := fz.FzReload(); err != nil {
+		t.Errorf("FzReload err=%v", err)
+	}
+	if logins != 2 || len(events) != 1 {
+		t.Fatalf("FzReload logins=%d events=%d", logins, len(events))
+	}
+	if !errors.Is(events[0].Cause, ErrSessionExpired) || events[0].Err != nil {
+		t.Errorf("FzReload event=%+v", events[0])
+	}
+	// 認証情報がない場合は再ログインしない
+	fz = &FzAPI{}
+	logins = 0
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	if err := fz.FzReload(); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("FzReload without credentials err=%v", err)
+	}
+	if logins != 1 {
+		t.Errorf("FzReload without credentials logins=%d", logins)
+	}
+	// リダイレクトされていないXML以外の応答は期限切れにしない
+	if err := fz.FzDeleteFile("k1"); err == nil || errors.Is(err, ErrSessionExpired) {
+		t.Errorf("FzDeleteFile html err=%v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package fzapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultRetryStatus : 再試行するHTTPのステータスの既定値
+var defaultRetryStatus = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// RetryPolicy : 一時的な失敗の再試行の設定
+// 再試行するのは、冪等なリクエスト（ダウンロード、エクスポート、一覧の取得など）と
+// 冪等でないリクエストでも接続できなかった場合だけ。
+// アップロードなどの送信中に失敗した場合は、重複を避けるため再試行しない。
+// 本文を送り直せないリクエスト（ファイルをパイプで送信するアップロードなど）は、接続できなかった場合も再試行しない
+type RetryPolicy struct {
+	MaxAttempts int           // 最大の送信回数（0または1の場合は再試行しない）
+	BaseDelay   time.Duration // 最初の再試行までの待ち時間（再試行毎に倍にする）
+	MaxDelay    time.Duration // 待ち時間の上限（0の場合は上限なし）
+	Jitter      float64       // 待ち時間をランダムに減らす割合(0から1)
+	RetryStatus []int         // 再試行するHTTPのステータス（nilの場合は502,503,504）
+	// 冪等でないリクエストも、ステータスや通信エラーで再試行する
+	RetryNonIdempotent bool
+}
+
+// delay : 再試行までの待ち時間（attemptは失敗した送信の回数）
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(float64(d) * p.Jitter * rand.Float64())
+	}
+	return d
+}
+
+// retryStatus : 再試行するステータスか判定する
+func (p RetryPolicy) retryStatus(status int) bool {
+	list := p.RetryStatus
+	if list == nil {
+		list = defaultRetryStatus
+	}
+	for _, s := range list {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRetry : 応答またはエラーから再試行するか判定する
+func (p RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	// 本文を送り直せない場合（失敗した時点で本文は閉じられているため、接続できなかった場合も送り直せない）
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	idempotent := p.RetryNonIdempotent || isIdempotent(req.Context())
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		if idempotent {
+			return true
+		}
+		// 接続できなかった場合は、リクエストが届いていない
+		var op *net.OpError
+		return errors.As(err, &op) && op.Op == "dial"
+	}
+	return idempotent && p.retryStatus(resp.StatusCode)
+}
+
+// retryAfter : 応答のRetry-After(秒)
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	n, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
+
+// doRequestWithRetry : RetryPolicyに従って再試行しながらリクエストを送信する
+func (fz *FzAPI) doRequestWithRetry(c *http.Client, req *http.Request) (*http.Response, error) {
+	p := fz.Retry
+	for attempt := 1; ; attempt++ {
+		resp, err := fz.doLogged(c, req)
+		if err != nil {
+			err = &netError{err: err}
+		}
+		if attempt >= p.MaxAttempts || !p.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+		d := p.delay(attempt)
+		fields := LogFields{"path": req.URL.Path, "attempt": attempt, "delay": d}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		if resp != nil {
+			if ra := retryAfter(resp); ra > d {
+				d = ra
+				fields["delay"] = d
+			}
+			fields["status"] = resp.StatusCode
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		fz.log(LogWarn, "retry", fields)
+		t := time.NewTimer(d)
+		select {
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, &netError{err: req.Context().Err()}
+		case <-t.C:
+		}
+		next := req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			next.Body = body
+		}
+		req = next
+	}
+}
+
+type idempotentKey struct{}
+
+// withIdempotent : 再試行しても問題のないリクエストであることをコンテキストに保存する
+func withIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// isIdempotent : 再試行しても問題のないリクエストか判定する
+func isIdempotent(ctx context.Context) bool {
+	b, _ := ctx.Value(idempotentKey{}).(bool)
+	return b
+}
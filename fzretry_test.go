@@ -0,0 +1,75 @@
+package fzapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRetryPolicy : 一時的な失敗の再試行の試験
+func TestRetryPolicy(t *testing.T) {
+	downloads, uploads := 0, 0
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"Mainmenu_file/download": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			downloads++
+			if downloads < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(form.Get("key"))))
+			w.Write([]byte(form.Get("key")))
+		},
+		"Mainmenu_upload/do_upload": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			uploads++
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	})
+	defer ts.Close()
+	fz := &FzAPI{}
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	w := &bytes.Buffer{}
+	if err := fz.FzDownloadWriter(context.Background(), "k1", w); err == nil {
+		t.Error("FzDownloadWriter no retry policy no err")
+	}
+	fz.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, Jitter: 0.5}
+	downloads = 0
+	w.Reset()
+	if err := fz.FzDownloadWriter(context.Background(), "k1", w); err != nil {
+		t.Errorf("FzDownloadWriter err=%v", err)
+	}
+	if downloads != 3 || w.String() != "k1" {
+		t.Errorf("FzDownloadWriter downloads=%d data=%q", downloads, w.String())
+	}
+	// アップロードは重複しないように再試行しない
+	if err := fz.FzUploadReader(context.Background(), strings.NewReader("test"), "test.txt", 4, "1", "test.txt", "", "", ""); err == nil {
+		t.Error("FzUploadReader no err")
+	}
+	if uploads != 1 {
+		t.Errorf("FzUploadReader uploads=%d", uploads)
+	}
+	// 接続できなかった場合は、本文を送り直せるリクエストだけ再試行する
+	dialErr := &netError{err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	form, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader("a=b"))
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	pipe, _ := http.NewRequest(http.MethodPost, ts.URL, pr)
+	if !fz.Retry.shouldRetry(form, nil, dialErr) || fz.Retry.shouldRetry(pipe, nil, dialErr) {
+		t.Error("shouldRetry dial error")
+	}
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if d := p.delay(i + 1); d != want {
+			t.Errorf("delay(%d)=%v want=%v", i+1, d, want)
+		}
+	}
+}
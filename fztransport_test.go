@@ -0,0 +1,68 @@
+package fzapi
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// countTransport : リクエストを数えるRoundTripper
+type countTransport struct {
+	n int
+}
+
+func (c *countTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.n++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// TestTransportOptions : 通信の設定の試験
+func TestTransportOptions(t *testing.T) {
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("puser:ppass"))
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"Login/auth": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			if r.Host != "filezen.invalid" || r.Header.Get("Proxy-Authorization") != auth {
+				w.WriteHeader(http.StatusProxyAuthRequired)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "SessionID", Value: "sid"})
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		},
+	})
+	defer ts.Close()
+	proxy, err := ProxyURL(ts.URL, "puser", "ppass")
+	if err != nil {
+		t.Fatalf("ProxyURL err=%v", err)
+	}
+	fz := &FzAPI{}
+	fz.TransportOptions.Proxy = proxy
+	fz.TransportOptions.TLSMinVersion = tls.VersionTLS12
+	if err := fz.FzLogin("http://filezen.invalid", "admin", "admin"); err != nil {
+		t.Errorf("FzLogin with proxy err=%v", err)
+	}
+	if _, err := ProxyURL("http://", "", ""); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("ProxyURL invalid err=%v", err)
+	}
+	if u, err := ProxyURL("proxy.example.com:8080", "", ""); err != nil || u.String() != "http://proxy.example.com:8080" {
+		t.Errorf("ProxyURL no scheme=%v err=%v", u, err)
+	}
+	// 独自のRoundTripper
+	ct := &countTransport{}
+	fz2 := &FzAPI{Transport: ct}
+	if err := fz2.FzLogin(ts.URL, "admin", "admin"); err == nil {
+		t.Error("FzLogin without proxy no err")
+	}
+	if ct.n != 1 {
+		t.Errorf("Transport count=%d", ct.n)
+	}
+	if v, err := ParseTLSVersion("1.3"); err != nil || v != tls.VersionTLS13 {
+		t.Errorf("ParseTLSVersion=%x err=%v", v, err)
+	}
+	if _, err := ParseTLSVersion("ssl3"); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("ParseTLSVersion invalid err=%v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package fzapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// NotifyTarget : アップロードをメールで通知する相手(mail_send)
+type NotifyTarget int
+
+// アップロードをメールで通知する相手
+const (
+	NotifyNone       NotifyTarget = iota // 通知しない
+	NotifyAll                            // プロジェクトの全員に通知する
+	NotifyIndividual                     // 個別に指定したユーザーに通知する
+)
+
+// String : 通知する相手の文字列
+func (t NotifyTarget) String() string {
+	switch t {
+	case NotifyNone:
+		return "NONE"
+	case NotifyAll:
+		return "ALL"
+	case NotifyIndividual:
+		return "INDIVIDUAL"
+	}
+	return fmt.Sprintf("NotifyTarget(%d)", int(t))
+}
+
+// NotifyEvent : アップロードしたファイルの操作を通知するイベント
+type NotifyEvent int
+
+// アップロードしたファイルの操作を通知するイベント
+const (
+	NotifyDownload NotifyEvent = 1 << iota // ダウンロードされた時(notify_download)
+	NotifyAlter                            // 変更された時(notify_alter)
+	NotifyDelete                           // 削除された時(notify_delete)
+
+	NotifyAllEvents = NotifyDownload | NotifyAlter | NotifyDelete
+)
+
+// notifyEventNames : イベントの文字列
+var notifyEventNames = []struct {
+	e    NotifyEvent
+	name string
+}{
+	{NotifyDownload, "DOWNLOAD"},
+	{NotifyAlter, "ALTER"},
+	{NotifyDelete, "DELETE"},
+}
+
+// String : イベントの文字列(DOWNLOAD|ALTER|DELETE)
+func (e NotifyEvent) String() string {
+	names := []string{}
+	for _, n := range notifyEventNames {
+		if e&n.e != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// UploadOptions : ファイルをアップロードする時の設定
+// FzUploadWithOptionsとFzPlUploadWithOptionsなどで共通に使用する
+type UploadOptions struct {
+	RegName     string       // 登録名(reg_filename)
+	Description string       // 説明(description)
+	Notify      NotifyTarget // メールで通知する相手(mail_send)
+	Events      NotifyEvent  // 操作を通知するイベント
+	NoNewAlert  bool         // 新着として表示しない(new_alert=0)
+
+	legacy bool // NewUploadOptionsで作成した（従来と同じく個別通知を許可する）
+}
+
+// NewUploadOptions : 従来のnotifyTo、notifyModeの文字列から設定を作成する
+// 従来と同じく、notifyToはALLを含む場合は全員、空の場合は通知しない、それ以外は個別通知（mail_send=2）、
+// notifyModeはDOWNLOAD、ALTER、DELETEを含むかで判断する（ALLはどのイベントも含まない）
+func NewUploadOptions(regName, comment, notifyTo, notifyMode string) UploadOptions {
+	o := UploadOptions{RegName: regName, Description: comment, legacy: true}
+	switch {
+	case strings.Contains(notifyTo, "ALL"):
+		o.Notify = NotifyAll
+	case notifyTo == "":
+		o.Notify = NotifyNone
+	default:
+		o.Notify = NotifyIndividual
+	}
+	for _, n := range notifyEventNames {
+		if strings.Contains(notifyMode, n.name) {
+			o.Events |= n.e
+		}
+	}
+	return o
+}
+
+// Validate : 設定を確認する
+func (o UploadOptions) Validate() error {
+	if o.Notify < NotifyNone || o.Notify > NotifyIndividual {
+		return fmt.Errorf("Invalid notify target %d: %w", int(o.Notify), ErrInvalidParam)
+	}
+	if o.Notify == NotifyIndividual && !o.legacy {
+		return fmt.Errorf("Individual notification is not supported (recipients cannot be specified): %w", ErrInvalidParam)
+	}
+	if o.Events&^NotifyAllEvents != 0 {
+		return fmt.Errorf("Invalid notify events %d: %w", int(o.Events), ErrInvalidParam)
+	}
+	if strings.ContainsAny(o.RegName, "/\\") {
+		return fmt.Errorf("Invalid registered name %s: %w", o.RegName, ErrInvalidParam)
+	}
+	return nil
+}
+
+// addFields : アップロードのフォームの項目を追加する
+func (o UploadOptions) addFields(add func(k, v string)) {
+	add("reg_filename", o.RegName)
+	add("description", o.Description)
+	add("mail_send", fmt.Sprintf("%d", int(o.Notify)))
+	for _, n := range []struct {
+		e   NotifyEvent
+		key string
+	}{
+		{NotifyDownload, "notify_download"},
+		{NotifyAlter, "notify_alter"},
+		{NotifyDelete, "notify_delete"},
+	} {
+		if o.Events&n.e != 0 {
+			add(n.key, "1")
+		} else {
+			add(n.key, "0")
+		}
+	}
+	if o.NoNewAlert {
+		add("new_alert", "0")
+	} else {
+		add("new_alert", "1")
+	}
+}
+
+// FzUploadWithOptions : 設定を指定してFileZenへ１つのリクエストでファイルをアップロードする
+func (fz *FzAPI) FzUploadWithOptions(ctx context.Context, localFile, folderID string, opts UploadOptions) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("FzUpload - UploadOptions Error: %w", err)
+	}
+	return fz.withRelogin(ctx, false, func() error {
+		return fz.fzUpload(ctx, localFile, folderID, opts)
+	})
+}
+
+// FzUploadReaderWithOptions : 設定を指定してReaderの内容を１つのリクエストでFileZenへアップロードする
+// nameはFileZen上のファイル名、sizeが不明な場合は-1を指定する
+func (fz *FzAPI) FzUploadReaderWithOptions(ctx context.Context, r io.Reader, name string, size int64, folderID string, opts UploadOptions) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("FzUpload - UploadOptions Error: %w", err)
+	}
+	return fz.withRelogin(ctx, false, func() error {
+		return fz.fzUploadReader(ctx, r, name, size, folderID, opts)
+	})
+}
+
+// FzPlUploadWithOptions : 設定を指定してFileZenへファイルを分割アップロードする
+func (fz *FzAPI) FzPlUploadWithOptions(ctx context.Context, localFile, folderID string, opts UploadOptions) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("FzPlUpload - UploadOptions Error: %w", err)
+	}
+	return fz.withRelogin(ctx, false, func() error {
+		return fz.fzPlUpload(ctx, localFile, folderID, opts)
+	})
+}
+
+// FzPlUploadReaderWithOptions : 設定を指定してReaderの内容を分割したリクエストでFileZenへアップロードする
+// nameはFileZen上のファイル名、sizeはReaderから読み込むサイズ（必須）
+func (fz *FzAPI) FzPlUploadReaderWithOptions(ctx context.Context, r io.Reader, name string, size int64, folderID string, opts UploadOptions) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("FzPlUpload - UploadOptions Error: %w", err)
+	}
+	return fz.withRelogin(ctx, false, func() error {
+		return fz.fzPlUploadReader(ctx, r, name, size, folderID, opts)
+	})
+}
+
+// FzPlUploadResumeWithOptions : 設定を指定して状態をファイルに保存しながら分割アップロードする
+func (fz *FzAPI) FzPlUploadResumeWithOptions(ctx context.Context, stateFile, localFile, folderID string, opts UploadOptions) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("FzPlUploadResume - UploadOptions Error: %w", err)
+	}
+	return fz.withRelogin(ctx, false, func() error {
+		return fz.fzPlUploadResume(ctx, stateFile, localFile, folderID, opts)
+	})
+}
@@ -0,0 +1,163 @@
+package fzapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestUploadOptions : アップロードの設定の試験
+func TestUploadOptions(t *testing.T) {
+	var forms []url.Values
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"Mainmenu_upload/plupload": func(w http.ResponseWriter, r *http.Request, form url.Values) {},
+		"Mainmenu_upload/do_upload": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			forms = append(forms, form)
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		},
+	})
+	defer ts.Close()
+	fz := &FzAPI{}
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	ctx := context.Background()
+	opts := UploadOptions{RegName: "report.csv", Description: "desc", Notify: NotifyAll, Events: NotifyDownload | NotifyDelete, NoNewAlert: true}
+	if err := fz.FzUploadReaderWithOptions(ctx, strings.NewReader("data"), "report.csv", 4, "1", opts); err != nil {
+		t.Fatalf("FzUploadReaderWithOptions err=%v", err)
+	}
+	if err := fz.FzPlUploadReaderWithOptions(ctx, strings.NewReader("data"), "report.csv", 4, "1", opts); err != nil {
+		t.Fatalf("FzPlUploadReaderWithOptions err=%v", err)
+	}
+	want := map[string]string{
+		"reg_filename":    "report.csv",
+		"description":     "desc",
+		"mail_send":       "1",
+		"notify_download": "1",
+		"notify_alter":    "0",
+		"notify_delete":   "1",
+		"new_alert":       "0",
+	}
+	if len(forms) != 2 {
+		t.Fatalf("do_upload forms=%d", len(forms))
+	}
+	for i, form := range forms {
+		for k, v := range want {
+			if form.Get(k) != v {
+				t.Errorf("form %d %s=%s want=%s", i, k, form.Get(k), v)
+			}
+		}
+	}
+	// 誤りは送信前にエラーにする
+	forms = nil
+	if err := fz.FzPlUploadReaderWithOptions(ctx, strings.NewReader("data"), "report.csv", 4, "1", UploadOptions{Notify: 3}); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("FzPlUploadReaderWithOptions err=%v", err)
+	}
+	if err := fz.FzUploadReaderWithOptions(ctx, strings.NewReader("data"), "report.csv", 4, "1", UploadOptions{RegName: "a/b.csv"}); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("FzUploadReaderWithOptions err=%v", err)
+	}
+	if len(forms) != 0 {
+		t.Errorf("invalid options posted %d", len(forms))
+	}
+	c := &FzcConfig{NotifyTo: "ALL", NotifyMode: "DOWNLOAD"}
+	if o, err := c.UploadOptions("a.txt", "c"); err != nil || o.Notify != NotifyAll || o.Events != NotifyDownload || o.RegName != "a.txt" {
+		t.Errorf("FzcConfig.UploadOptions opts=%+v err=%v", o, err)
+	}
+}
+
+// TestUploadLegacyNotify : 従来のnotifyTo、notifyModeの文字列の互換性の試験
+func TestUploadLegacyNotify(t *testing.T) {
+	var forms []url.Values
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"Mainmenu_upload/do_upload": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			forms = append(forms, form)
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		},
+	})
+	defer ts.Close()
+	fz := &FzAPI{}
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	var tests = []struct {
+		to, mode                       string
+		mailSend, download, alter, del string
+	}{
+		{to: "", mode: "", mailSend: "0", download: "0", alter: "0", del: "0"},
+		{to: "ALL", mode: "DOWNLOAD/DELETE", mailSend: "1", download: "1", alter: "0", del: "1"},
+		{to: "ALL|AUTO", mode: "DOWNLOAD|ALTER|DELETE", mailSend: "1", download: "1", alter: "1", del: "1"},
+		// ALL以外は個別通知、ALLはどのイベントも含まない
+		{to: "2", mode: "ALL", mailSend: "2", download: "0", alter: "0", del: "0"},
+		{to: "AUTO", mode: "", mailSend: "2", download: "0", alter: "0", del: "0"},
+		{to: "NONE", mode: "", mailSend: "2", download: "0", alter: "0", del: "0"},
+		// 大文字と小文字は区別する
+		{to: "all", mode: "download", mailSend: "2", download: "0", alter: "0", del: "0"},
+		// 誤った文字列も以前と同じくエラーにしない
+		{to: "ALL", mode: "DOWNLAOD", mailSend: "1", download: "0", alter: "0", del: "0"},
+	}
+	ctx := context.Background()
+	for _, e := range tests {
+		forms = nil
+		if err := fz.FzUploadReader(ctx, strings.NewReader("data"), "report.csv", 4, "1", "report.csv", "", e.to, e.mode); err != nil {
+			t.Errorf("FzUploadReader to=%s mode=%s err=%v", e.to, e.mode, err)
+			continue
+		}
+		if len(forms) != 1 {
+			t.Fatalf("FzUploadReader forms=%d", len(forms))
+		}
+		f := forms[0]
+		if f.Get("mail_send") != e.mailSend || f.Get("notify_download") != e.download || f.Get("notify_alter") != e.alter || f.Get("notify_delete") != e.del {
+			t.Errorf("FzUploadReader to=%s mode=%s form=%v", e.to, e.mode, f)
+		}
+	}
+}
+
+// TestUploadIndividual : 個別通知の試験
+func TestUploadIndividual(t *testing.T) {
+	var forms []url.Values
+	ts := newFakeFz(t, map[string]fakeFzHandler{
+		"Mainmenu_upload/do_upload": func(w http.ResponseWriter, r *http.Request, form url.Values) {
+			forms = append(forms, form)
+			fmt.Fprintf(w, fakeFzResp, "OK")
+		},
+	})
+	defer ts.Close()
+	fz := &FzAPI{}
+	if err := fz.FzLogin(ts.URL, "admin", "admin"); err != nil {
+		t.Fatalf("FzLogin err=%v", err)
+	}
+	ctx := context.Background()
+	// 通知するユーザーを指定できないため、直接作成した個別通知はエラー
+	opts := UploadOptions{RegName: "report.csv", Notify: NotifyIndividual}
+	if err := opts.Validate(); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("Validate err=%v", err)
+	}
+	if err := fz.FzUploadReaderWithOptions(ctx, strings.NewReader("data"), "report.csv", 4, "1", opts); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("FzUploadReaderWithOptions err=%v", err)
+	}
+	if len(forms) != 0 {
+		t.Errorf("do_upload forms=%d", len(forms))
+	}
+	// 従来のAPIは個別通知でmail_send=2だけを送信する
+	for _, to := range []string{"INDIVIDUAL", "AUTO", "2"} {
+		if err := fz.FzUploadReader(ctx, strings.NewReader("data"), "report.csv", 4, "1", "report.csv", "", to, ""); err != nil {
+			t.Errorf("FzUploadReader %s err=%v", to, err)
+		}
+	}
+	for i, form := range forms {
+		if form.Get("mail_send") != "2" {
+			t.Errorf("legacy form %d mail_send=%s", i, form.Get("mail_send"))
+		}
+	}
+	if len(forms) != 3 {
+		t.Errorf("legacy forms=%d", len(forms))
+	}
+	c := &FzcConfig{NotifyTo: "AUTO"}
+	if o, err := c.UploadOptions("a.txt", ""); err != nil || o.Notify != NotifyIndividual {
+		t.Errorf("FzcConfig.UploadOptions AUTO opts=%+v err=%v", o, err)
+	}
+}
@@ -0,0 +1,151 @@
+package fzapi
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SkipFolder : WalkFuncが返すと、そのフォルダ内のファイルとサブフォルダを飛ばす
+var SkipFolder = errors.New("skip this folder")
+
+// WalkFunc : Walkで呼び出す関数
+// フォルダの場合はfがnilで、その後にフォルダ内のファイル、サブフォルダの順に呼び出す
+type WalkFunc func(p FzPath, d *XMLFolder, f *XMLFile) error
+
+// Walk : LastRespの全てのプロジェクト、フォルダ、ファイルを順に辿る
+// fnがSkipFolder以外のエラーを返した場合は、そのエラーを返して終了する
+func (fz *FzAPI) Walk(fn WalkFunc) error {
+	for _, prj := range fz.LastResponse().ProjectList {
+		for _, d := range prj.FolderList {
+			err := walkFolder(FzPath{Project: prj.Name}, d, fn)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// walkFolder : フォルダを辿る
+func walkFolder(parent FzPath, d *XMLFolder, fn WalkFunc) error {
+	folders := make([]string, len(parent.Folders), len(parent.Folders)+1)
+	copy(folders, parent.Folders)
+	p := FzPath{Project: parent.Project, Folders: append(folders, d.Name)}
+	if err := fn(p, d, nil); err != nil {
+		if errors.Is(err, SkipFolder) {
+			return nil
+		}
+		return err
+	}
+	for _, f := range d.FileList {
+		if err := fn(p, d, f); err != nil {
+			if errors.Is(err, SkipFolder) {
+				return nil
+			}
+			return err
+		}
+	}
+	for _, s := range d.SubFolders {
+		if err := walkFolder(p, s, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchFzFileEnt : ファイルの検索のパラメータ
+// 名前のパターンはglob（path.Matchの形式）、Regexpがtrueの場合は正規表現。空の場合は全てに一致する
+type SearchFzFileEnt struct {
+	Project string    // プロジェクト名のパターン
+	Folder  string    // フォルダのパターン（「フォルダ/サブフォルダ」のエスケープした形式に対して判定する）
+	File    string    // ファイル名のパターン
+	Owner   string    // 所有者のパターン
+	Regexp  bool      // パターンを正規表現として扱う
+	MinSize int64     // 最小のサイズ（バイト）
+	MaxSize int64     // 最大のサイズ（バイト、0は制限なし）
+	After   time.Time // この日時以降に登録されたファイル
+	Before  time.Time // この日時より前に登録されたファイル
+}
+
+// FzFileMatch : 検索に一致したファイル
+type FzFileMatch struct {
+	Path   FzPath // ファイルがあるフォルダのパス
+	Folder *XMLFolder
+	File   *XMLFile
+	Info   *FileInfo
+}
+
+// nameMatcher : 名前のパターンの判定
+type nameMatcher func(name string) bool
+
+// newNameMatcher : パターンから判定の関数を作成する
+func newNameMatcher(pattern string, useRegexp bool) (nameMatcher, error) {
+	if pattern == "" {
+		return func(string) bool { return true }, nil
+	}
+	if useRegexp {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regexp %s: %w", pattern, ErrInvalidParam)
+		}
+		return re.MatchString, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("Invalid pattern %s: %w", pattern, ErrInvalidParam)
+	}
+	return func(name string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}, nil
+}
+
+// SearchFzFile : LastRespから条件に一致する全てのファイルを検索する
+// サイズや日時などを変換できないファイルは、警告のログを出力して飛ばす
+func (fz *FzAPI) SearchFzFile(s *SearchFzFileEnt) ([]*FzFileMatch, error) {
+	if s.MaxSize != 0 && s.MaxSize < s.MinSize {
+		return nil, fmt.Errorf("SearchFzFile - Max size %d < min size %d: %w", s.MaxSize, s.MinSize, ErrInvalidParam)
+	}
+	matchers := []nameMatcher{}
+	for _, pattern := range []string{s.Project, s.Folder, s.File, s.Owner} {
+		m, err := newNameMatcher(pattern, s.Regexp)
+		if err != nil {
+			return nil, fmt.Errorf("SearchFzFile - %w", err)
+		}
+		matchers = append(matchers, m)
+	}
+	matchProject, matchFolder, matchFile, matchOwner := matchers[0], matchers[1], matchers[2], matchers[3]
+	ret := []*FzFileMatch{}
+	err := fz.Walk(func(p FzPath, d *XMLFolder, f *XMLFile) error {
+		if !matchProject(p.Project) {
+			return SkipFolder
+		}
+		if f == nil || !matchFile(f.Name) || !matchOwner(f.Owner) {
+			return nil
+		}
+		folder := strings.TrimPrefix(p.String(), escapeFzName(p.Project)+"/")
+		if !matchFolder(folder) {
+			return nil
+		}
+		info, err := f.Info()
+		if err != nil {
+			fz.log(LogWarn, "search skip", LogFields{"folder": p.String(), "file": f.Name, "error": err.Error()})
+			return nil
+		}
+		if info.Size < s.MinSize || (s.MaxSize != 0 && info.Size > s.MaxSize) {
+			return nil
+		}
+		if (!s.After.IsZero() && info.ModTime.Before(s.After)) || (!s.Before.IsZero() && !info.ModTime.Before(s.Before)) {
+			return nil
+		}
+		ret = append(ret, &FzFileMatch{Path: p, Folder: d, File: f, Info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
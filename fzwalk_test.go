@@ -0,0 +1,140 @@
+package fzapi
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fakeWalkResp = `<?xml version="1.0" encoding="UTF-8"?>
+<FileZen>
+<Lastop><Res>OK</Res></Lastop>
+<ProjectList>
+<Project Name="Sales">
+<Folder Name="Reports" Id="1" Access="read">
+<File Name="2020-01.csv" Key="K1" Owner="alice" Size="100" TimeStamp="1577836800"/>
+<File Name="2020-02.csv" Key="K2" Owner="bob" Size="2048" TimeStamp="1580515200"/>
+<Folder Name="Old" Id="2" Access="read">
+<File Name="2019-12.csv" Key="K3" Owner="alice" Size="10" TimeStamp="1575158400"/>
+</Folder>
+</Folder>
+</Project>
+<Project Name="Dev">
+<Folder Name="Build" Id="3" Access="read,write">
+<File Name="app.zip" Key="K4" Owner="carol" Size="1048576" TimeStamp="1577836800"/>
+</Folder>
+</Project>
+</ProjectList>
+</FileZen>`
+
+// TestWalk : プロジェクト、フォルダ、ファイルを辿る試験
+func TestWalk(t *testing.T) {
+	fz := &FzAPI{LastResp: &XMLFileZen{}}
+	if err := xml.Unmarshal([]byte(fakeWalkResp), fz.LastResp); err != nil {
+		t.Fatal(err)
+	}
+	visited := []string{}
+	err := fz.Walk(func(p FzPath, d *XMLFolder, f *XMLFile) error {
+		if f == nil {
+			visited = append(visited, p.String())
+			return nil
+		}
+		visited = append(visited, f.Key)
+		return nil
+	})
+	want := "Sales/Reports,K1,K2,Sales/Reports/Old,K3,Dev/Build,K4"
+	if err != nil || strings.Join(visited, ",") != want {
+		t.Errorf("Walk visited=%v err=%v", visited, err)
+	}
+	// SkipFolder
+	visited = nil
+	fz.Walk(func(p FzPath, d *XMLFolder, f *XMLFile) error {
+		if f == nil {
+			visited = append(visited, p.String())
+			if d.Name == "Reports" {
+				return SkipFolder
+			}
+		}
+		return nil
+	})
+	if strings.Join(visited, ",") != "Sales/Reports,Dev/Build" {
+		t.Errorf("Walk SkipFolder visited=%v", visited)
+	}
+	// ラップしたSkipFolder
+	visited = nil
+	fz.Walk(func(p FzPath, d *XMLFolder, f *XMLFile) error {
+		if f == nil {
+			visited = append(visited, p.String())
+			if d.Name == "Reports" {
+				return fmt.Errorf("skip %s: %w", d.Name, SkipFolder)
+			}
+		}
+		return nil
+	})
+	if strings.Join(visited, ",") != "Sales/Reports,Dev/Build" {
+		t.Errorf("Walk wrapped SkipFolder visited=%v", visited)
+	}
+	stop := errors.New("stop")
+	if err := fz.Walk(func(p FzPath, d *XMLFolder, f *XMLFile) error { return stop }); err != stop {
+		t.Errorf("Walk stop err=%v", err)
+	}
+}
+
+// TestSearchFzFile : ファイルの検索の試験
+func TestSearchFzFile(t *testing.T) {
+	fz := &FzAPI{LastResp: &XMLFileZen{}}
+	if err := xml.Unmarshal([]byte(fakeWalkResp), fz.LastResp); err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range []struct {
+		s    SearchFzFileEnt
+		want string
+	}{
+		{SearchFzFileEnt{}, "K1,K2,K3,K4"},
+		{SearchFzFileEnt{File: "*.csv"}, "K1,K2,K3"},
+		{SearchFzFileEnt{Project: "Sales", Folder: "Reports"}, "K1,K2"},
+		{SearchFzFileEnt{Folder: "Reports/*"}, "K3"},
+		{SearchFzFileEnt{Owner: "alice"}, "K1,K3"},
+		{SearchFzFileEnt{File: `^2020-0[12]\.csv$`, Regexp: true}, "K1,K2"},
+		{SearchFzFileEnt{MinSize: 1000}, "K2,K4"},
+		{SearchFzFileEnt{MinSize: 50, MaxSize: 2048}, "K1,K2"},
+		{SearchFzFileEnt{After: time.Unix(1577836800, 0)}, "K1,K2,K4"},
+		{SearchFzFileEnt{After: time.Unix(1577836800, 0), Before: time.Unix(1580515200, 0)}, "K1,K4"},
+		{SearchFzFileEnt{Project: "None"}, ""},
+	} {
+		ms, err := fz.SearchFzFile(&c.s)
+		if err != nil {
+			t.Errorf("SearchFzFile %d err=%v", i, err)
+			continue
+		}
+		keys := []string{}
+		for _, m := range ms {
+			keys = append(keys, m.Info.Key)
+		}
+		if strings.Join(keys, ",") != c.want {
+			t.Errorf("SearchFzFile %d keys=%v want=%s", i, keys, c.want)
+		}
+	}
+	ms, _ := fz.SearchFzFile(&SearchFzFileEnt{File: "2019-12.csv"})
+	if len(ms) != 1 || ms[0].Path.String() != "Sales/Reports/Old" || ms[0].Folder.ID != "2" || ms[0].Info.Size != 10 {
+		t.Errorf("SearchFzFile match=%+v", ms)
+	}
+	// 変換できないファイルは飛ばす
+	var skipped []string
+	fz.Logger = LoggerFunc(func(level LogLevel, msg string, fields LogFields) {
+		skipped = append(skipped, fields["file"].(string))
+	})
+	fz.LastResponse().ProjectList[0].FolderList[0].FileList[1].Size = "2K"
+	ms, err := fz.SearchFzFile(&SearchFzFileEnt{File: "*.csv"})
+	if err != nil || len(ms) != 2 || strings.Join(skipped, ",") != "2020-02.csv" {
+		t.Errorf("SearchFzFile invalid size matches=%d skipped=%v err=%v", len(ms), skipped, err)
+	}
+	for _, s := range []SearchFzFileEnt{{File: "["}, {File: "(", Regexp: true}, {MinSize: 10, MaxSize: 5}} {
+		if _, err := fz.SearchFzFile(&s); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("SearchFzFile %+v err=%v", s, err)
+		}
+	}
+}